@@ -0,0 +1,154 @@
+package gee
+
+//认证中间件部分
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+//认证通过后的主体在Context中的键名,BasicAuth中为用户名,其余为验证函数返回的主体
+const AuthPrincipalKey = "gee/principal"
+
+//BasicAuth的用户名到密码的映射
+type Accounts map[string]string
+
+//验证令牌或API Key并返回认证主体的函数,ok为false表示验证失败
+type TokenValidator func(token string) (principal interface{}, ok bool)
+
+//返回使用默认域"Authorization Required"的HTTP Basic认证中间件函数
+func BasicAuth(accounts Accounts) HandlerFunc {
+	return BasicAuthForRealm(accounts, "")
+}
+
+//返回HTTP Basic认证中间件函数,realm为认证域,为空时使用"Authorization Required"
+//认证通过时将用户名存入Context,失败时返回401并通过WWW-Authenticate要求认证
+func BasicAuthForRealm(accounts Accounts, realm string) HandlerFunc {
+	if len(accounts) == 0 {
+		panic("basic auth: empty list of authorized accounts")
+	}
+	if realm == "" {
+		realm = "Authorization Required"
+	}
+	challenge := "Basic realm=" + strconv.Quote(realm)
+	//预先计算各账号的Authorization首部值
+	type pair struct {
+		value []byte
+		user  string
+	}
+	pairs := make([]pair, 0, len(accounts))
+	for user, password := range accounts {
+		if user == "" || strings.Contains(user, ":") {
+			panic("basic auth: user can not be empty or contain ':'")
+		}
+		value := "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+		pairs = append(pairs, pair{[]byte(value), user})
+	}
+	return func(c *Context) {
+		auth := []byte(c.GetHeader("Authorization"))
+		user, found := "", false
+		//与所有账号逐一比较,耗时与匹配的账号无关
+		for _, p := range pairs {
+			if subtle.ConstantTimeCompare(auth, p.value) == 1 {
+				user, found = p.user, true
+			}
+		}
+		if !found {
+			unauthorized(c, challenge)
+			return
+		}
+		c.Set(AuthPrincipalKey, user)
+		c.Next()
+	}
+}
+
+//返回Bearer令牌认证中间件函数,令牌由validator验证
+//认证通过时将validator返回的主体存入Context,失败时返回401
+func BearerAuth(validator TokenValidator) HandlerFunc {
+	if validator == nil {
+		panic("bearer auth: validator can not be nil")
+	}
+	return func(c *Context) {
+		auth := c.GetHeader("Authorization")
+		if len(auth) <= len("Bearer ") || !strings.EqualFold(auth[:len("Bearer ")], "Bearer ") {
+			unauthorized(c, `Bearer realm="Restricted"`)
+			return
+		}
+		principal, ok := validator(strings.TrimSpace(auth[len("Bearer "):]))
+		if !ok {
+			unauthorized(c, `Bearer realm="Restricted", error="invalid_token"`)
+			return
+		}
+		c.Set(AuthPrincipalKey, principal)
+		c.Next()
+	}
+}
+
+//返回API Key认证中间件函数
+//lookup指定API Key的位置,格式为"header:<首部名>"或"query:<参数名>",如"header:X-API-Key"
+//认证通过时将validator返回的主体存入Context,失败时返回401
+func APIKey(lookup string, validator TokenValidator) HandlerFunc {
+	if validator == nil {
+		panic("api key: validator can not be nil")
+	}
+	source, name, _ := strings.Cut(lookup, ":")
+	if name == "" || (source != "header" && source != "query") {
+		panic("api key: lookup must be in the form of \"header:<name>\" or \"query:<name>\"")
+	}
+	challenge := "APIKey realm=\"Restricted\", " + source + "=" + strconv.Quote(name)
+	return func(c *Context) {
+		var key string
+		if source == "header" {
+			key = c.GetHeader(name)
+		} else {
+			key = c.Query(name)
+		}
+		if key == "" {
+			unauthorized(c, challenge)
+			return
+		}
+		principal, ok := validator(key)
+		if !ok {
+			unauthorized(c, challenge)
+			return
+		}
+		c.Set(AuthPrincipalKey, principal)
+		c.Next()
+	}
+}
+
+//返回以常量时间比较令牌的验证函数,tokens为令牌到认证主体的映射
+func StaticTokens(tokens map[string]interface{}) TokenValidator {
+	type pair struct {
+		token     []byte
+		principal interface{}
+	}
+	pairs := make([]pair, 0, len(tokens))
+	for token, principal := range tokens {
+		pairs = append(pairs, pair{[]byte(token), principal})
+	}
+	return func(token string) (interface{}, bool) {
+		var principal interface{}
+		found := false
+		for _, p := range pairs {
+			if subtle.ConstantTimeCompare([]byte(token), p.token) == 1 {
+				principal, found = p.principal, true
+			}
+		}
+		return principal, found
+	}
+}
+
+//获取认证通过的主体,未认证时返回nil
+func (c *Context) Principal() interface{} {
+	principal, _ := c.Get(AuthPrincipalKey)
+	return principal
+}
+
+//返回401并通过WWW-Authenticate首部要求认证
+func unauthorized(c *Context, challenge string) {
+	c.SetHeader("WWW-Authenticate", challenge)
+	c.AbortWithStatus(http.StatusUnauthorized)
+}
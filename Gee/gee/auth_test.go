@@ -0,0 +1,123 @@
+package gee
+
+import (
+	"encoding/base64"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBasicAuth(t *testing.T) {
+	r := New()
+	r.Use(BasicAuth(Accounts{"admin": "secret"}))
+	r.GET("/admin", func(c *Context) { c.String(200, "%v", c.Principal()) })
+
+	basic := func(user, password string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+	}
+	tests := []struct {
+		name     string
+		auth     string
+		wantCode int
+		wantBody string
+	}{
+		{"正确的账号", basic("admin", "secret"), 200, "admin"},
+		{"错误的密码", basic("admin", "wrong"), 401, ""},
+		{"未知的用户", basic("guest", "secret"), 401, ""},
+		{"缺少Authorization", "", 401, ""},
+		{"错误的认证方式", "Bearer secret", 401, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/admin", nil)
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.wantCode || w.Body.String() != tt.wantBody {
+			t.Errorf("%s: got %d %q, want %d %q", tt.name, w.Code, w.Body.String(), tt.wantCode, tt.wantBody)
+		}
+		challenge := w.Header().Get("WWW-Authenticate")
+		if tt.wantCode == 401 && challenge != `Basic realm="Authorization Required"` {
+			t.Errorf("%s: WWW-Authenticate = %q", tt.name, challenge)
+		}
+		if tt.wantCode == 200 && challenge != "" {
+			t.Errorf("%s: unexpected WWW-Authenticate %q", tt.name, challenge)
+		}
+	}
+}
+
+func TestBearerAuth(t *testing.T) {
+	r := New()
+	r.Use(BearerAuth(StaticTokens(map[string]interface{}{"token123": "alice"})))
+	r.GET("/me", func(c *Context) { c.String(200, "%v", c.Principal()) })
+
+	tests := []struct {
+		name          string
+		auth          string
+		wantCode      int
+		wantChallenge string
+	}{
+		{"正确的令牌", "Bearer token123", 200, ""},
+		{"前缀不区分大小写", "bearer token123", 200, ""},
+		{"错误的前缀", "Token token123", 401, `Bearer realm="Restricted"`},
+		{"缺少令牌", "Bearer ", 401, `Bearer realm="Restricted"`},
+		{"无效的令牌", "Bearer wrong", 401, `Bearer realm="Restricted", error="invalid_token"`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/me", nil)
+		req.Header.Set("Authorization", tt.auth)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.wantCode {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.wantCode)
+		}
+		if got := w.Header().Get("WWW-Authenticate"); got != tt.wantChallenge {
+			t.Errorf("%s: WWW-Authenticate = %q, want %q", tt.name, got, tt.wantChallenge)
+		}
+		if tt.wantCode == 200 && w.Body.String() != "alice" {
+			t.Errorf("%s: principal = %q", tt.name, w.Body.String())
+		}
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	validator := StaticTokens(map[string]interface{}{"key123": "service"})
+	r := New()
+	r.Group("/h").Use(APIKey("header:X-API-Key", validator))
+	r.Group("/q").Use(APIKey("query:key", validator))
+	handler := func(c *Context) { c.String(200, "%v", c.Principal()) }
+	r.GET("/h/data", handler)
+	r.GET("/q/data", handler)
+
+	tests := []struct {
+		name     string
+		url      string
+		header   string
+		wantCode int
+	}{
+		{"首部中的Key", "/h/data", "key123", 200},
+		{"首部中的错误Key", "/h/data", "wrong", 401},
+		{"首部中缺少Key", "/h/data", "", 401},
+		{"查询参数不用于首部方式", "/h/data?key=key123", "", 401},
+		{"查询参数中的Key", "/q/data?key=key123", "", 200},
+		{"查询参数中的错误Key", "/q/data?key=wrong", "", 401},
+		{"首部不用于查询参数方式", "/q/data", "key123", 401},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.url, nil)
+		if tt.header != "" {
+			req.Header.Set("X-API-Key", tt.header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.wantCode {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.wantCode)
+		}
+		if tt.wantCode == 200 && w.Body.String() != "service" {
+			t.Errorf("%s: principal = %q", tt.name, w.Body.String())
+		}
+		if tt.wantCode == 401 && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: missing WWW-Authenticate", tt.name)
+		}
+	}
+}
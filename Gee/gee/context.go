@@ -2,49 +2,214 @@ package gee
 
 //上下文部分
 import (
-	"encoding/json"
-	"fmt"
+	"context"
+	"encoding/xml"
+	"errors"
+	"gee/render"
+	"io"
+	"log/slog"
+	"math"
+	"mime"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 )
 
 type H map[string]interface{}
 
+//实现xml.Marshaler接口,使H能够编码为XML
+func (h H) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Space: "", Local: "map"}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for key, value := range h {
+		elem := xml.StartElement{
+			Name: xml.Name{Space: "", Local: key},
+			Attr: []xml.Attr{},
+		}
+		if err := e.EncodeElement(value, elem); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(xml.EndElement{Name: start.Name})
+}
+
+//中断后处理函数索引的哨兵值,大于任何可能的处理函数数量
+const abortIndex int = math.MaxInt32 >> 1
+
 //上下文结构体
 type Context struct {
-	Writer     http.ResponseWriter //响应
-	Request    *http.Request       //请求
-	Path       string              //请求的URL路径
-	Method     string              //请求的方法
-	StatusCode int                 //响应的状态码
-	Params     map[string]string   //动态路由参数表
-	handlers   []HandlerFunc       //处理函数集
-	index      int                 //处理函数索引
-	engine     *Engine             //框架主体指针
+	Writer     ResponseWriter         //响应
+	Request    *http.Request          //请求
+	Path       string                 //请求的URL路径
+	Method     string                 //请求的方法
+	//响应的状态码,仅由SetStatus设置,File、WebSocket升级等直接写出的状态码不会反映在此
+	//
+	//Deprecated: 使用Writer.Status()获取实际写出的状态码
+	StatusCode int
+	Params     map[string]string      //动态路由参数表
+	Keys       map[string]interface{} //请求内的键值对存储
+	Errors     errorMsgs              //处理过程中记录的错误
+	handlers   []HandlerFunc          //处理函数集
+	index      int                    //处理函数索引
+	engine     *Engine                //框架主体指针
+	mu         sync.RWMutex           //键值对存储的读写锁
+	writermem  responseWriter         //Writer实际指向的响应写入结构体
+	htmlRender render.HTMLRender      //请求所属分组的HTML渲染器
+	logger     *slog.Logger           //请求的结构化日志记录器,首次获取时创建
 }
 
 //Context构造函数
 func newContext(w http.ResponseWriter, req *http.Request) *Context {
-	return &Context{
-		Writer:  w,
+	c := &Context{
 		Request: req,
 		Path:    req.URL.Path,
 		Method:  req.Method,
 		index:   -1,
 	}
+	c.writermem.reset(w) //包装原始响应以记录状态码和写入状态
+	c.Writer = &c.writermem
+	return c
 }
 
 //依次执行中间件
 func (c *Context) Next() {
 	c.index++
-	for s := len(c.handlers); c.index < s; c.index++ {
+	//处理函数集可能在执行过程中被替换(如静态文件不存在时),因此每次重新获取长度
+	for ; c.index < len(c.handlers); c.index++ {
 		c.handlers[c.index](c)
 	}
 }
 
+//以未匹配路由的处理函数替换后续处理函数,用于处理函数内部发现资源不存在的情况
+func (c *Context) notFound() {
+	c.SetStatus(http.StatusNotFound)
+	c.handlers = append(c.handlers[:c.index+1:c.index+1], c.engine.noRouteHandlers()...)
+}
+
+//中断后续处理函数的执行,不影响当前处理函数
+func (c *Context) Abort() {
+	c.index = abortIndex //将处理函数索引设置为哨兵值
+}
+
+//判断处理是否已被中断
+func (c *Context) IsAborted() bool {
+	return c.index >= abortIndex
+}
+
+//中断执行并设置响应状态码
+func (c *Context) AbortWithStatus(code int) {
+	c.SetStatus(code)
+	c.Abort()
+}
+
+//中断执行并返回JSON类型响应
+func (c *Context) AbortWithStatusJSON(code int, obj interface{}) {
+	c.Abort()
+	c.JSON(code, obj)
+}
+
+//中断执行,设置响应状态码并记录错误,返回该错误以便链式处理
+func (c *Context) AbortWithError(code int, err error) error {
+	c.AbortWithStatus(code)
+	return c.Error(err)
+}
+
+//记录处理过程中的错误,返回包装后的错误以便设置类型和元数据
+//未指定类型的错误视为私有错误
+func (c *Context) Error(err error) *Error {
+	if err == nil {
+		panic("err is nil")
+	}
+	parsedError, ok := err.(*Error)
+	if !ok {
+		parsedError = &Error{
+			Err:  err,
+			Type: ErrorTypePrivate,
+		}
+	}
+	c.Errors = append(c.Errors, parsedError)
+	return parsedError
+}
+
 //执行失败中断中间件执行
 func (c *Context) Fail(code int, err string) {
-	c.index = len(c.handlers)       //将处理函数索引设置为终止值
-	c.JSON(code, H{"message": err}) //返回错误信息
+	c.AbortWithStatusJSON(code, H{"message": err}) //返回错误信息
+}
+
+//在上下文中存储键值对
+func (c *Context) Set(key string, value interface{}) {
+	c.mu.Lock()
+	if c.Keys == nil {
+		c.Keys = make(map[string]interface{})
+	}
+	c.Keys[key] = value
+	c.mu.Unlock()
+}
+
+//获取上下文中存储的键值对,exists表示键是否存在
+func (c *Context) Get(key string) (value interface{}, exists bool) {
+	c.mu.RLock()
+	value, exists = c.Keys[key]
+	c.mu.RUnlock()
+	return
+}
+
+//获取上下文中存储的键值对,不存在则引发错误
+func (c *Context) MustGet(key string) interface{} {
+	if value, exists := c.Get(key); exists {
+		return value
+	}
+	panic("key \"" + key + "\" does not exist")
+}
+
+//以下实现context.Context接口,均委托给请求的Context
+
+//返回请求的截止时间
+func (c *Context) Deadline() (deadline time.Time, ok bool) {
+	if c.Request == nil {
+		return
+	}
+	return c.Request.Context().Deadline()
+}
+
+//返回请求取消(客户端断开或超时)时关闭的通道
+func (c *Context) Done() <-chan struct{} {
+	if c.Request == nil {
+		return nil
+	}
+	return c.Request.Context().Done()
+}
+
+//返回请求被取消的原因,未取消则为nil
+func (c *Context) Err() error {
+	if c.Request == nil {
+		return nil
+	}
+	return c.Request.Context().Err()
+}
+
+//根据键返回值,优先查找请求的Context,未找到时字符串键再查找上下文的键值对存储
+func (c *Context) Value(key interface{}) interface{} {
+	if c.Request != nil {
+		if value := c.Request.Context().Value(key); value != nil {
+			return value
+		}
+	}
+	if keyAsString, ok := key.(string); ok {
+		if value, exists := c.Get(keyAsString); exists {
+			return value
+		}
+	}
+	return nil
+}
+
+//判断客户端是否已断开连接(请求已被取消),用于在耗时处理中提前结束
+func (c *Context) IsClientGone() bool {
+	return errors.Is(c.Err(), context.Canceled)
 }
 
 //获取动态路由的参数
@@ -62,45 +227,310 @@ func (c *Context) Query(key string) string {
 	return c.Request.URL.Query().Get(key)
 }
 
+//获取请求首部中键名key对应的值
+func (c *Context) GetHeader(key string) string {
+	return c.Request.Header.Get(key)
+}
+
+//获取客户端IP
+//仅当直接连接的对端为本机或内网地址(通常为反向代理)时才采信X-Forwarded-For和X-Real-Ip首部,
+//以防客户端伪造首部冒充其他IP
+func (c *Context) ClientIP() string {
+	remoteIP, _, err := net.SplitHostPort(strings.TrimSpace(c.Request.RemoteAddr))
+	if err != nil {
+		remoteIP = strings.TrimSpace(c.Request.RemoteAddr)
+	}
+	ip := net.ParseIP(remoteIP)
+	if ip == nil || !(ip.IsLoopback() || ip.IsPrivate()) {
+		return remoteIP
+	}
+	if forwarded := c.GetHeader("X-Forwarded-For"); forwarded != "" {
+		//从右向左跳过内网代理,左侧的值可能由客户端伪造
+		ips := strings.Split(forwarded, ",")
+		for i := len(ips) - 1; i >= 0; i-- {
+			client := strings.TrimSpace(ips[i])
+			ip := net.ParseIP(client)
+			if ip == nil {
+				break
+			}
+			if i == 0 || !(ip.IsLoopback() || ip.IsPrivate()) {
+				return client
+			}
+		}
+	}
+	if realIP := strings.TrimSpace(c.GetHeader("X-Real-Ip")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+	return remoteIP
+}
+
 //添加响应的首部
 func (c *Context) SetHeader(key string, value string) {
 	c.Writer.Header().Set(key, value)
 }
 
-//设置响应状态码
+//设置响应状态码,首部在首次写入响应体时才真正写出
 func (c *Context) SetStatus(code int) {
+	c.StatusCode = code
 	c.Writer.WriteHeader(code)
 }
 
+//以给定的渲染器构造响应,所有响应格式均经由此处写出
+//渲染结果先写入缓冲区,成功后才写出首部和响应体;
+//失败时记录错误并将状态码置为500,由错误处理中间件生成响应
+func (c *Context) Render(code int, r render.Render) {
+	c.SetStatus(code)
+	//该状态码不允许响应体时只写入首部
+	if !bodyAllowedForStatus(code) {
+		r.WriteContentType(c.Writer)
+		c.Writer.WriteHeaderNow()
+		return
+	}
+	//流式数据直接写入响应,不经过缓冲
+	if _, ok := r.(render.Reader); ok {
+		if err := r.Render(c.Writer); err != nil {
+			c.Error(err)
+			c.Abort()
+		}
+		return
+	}
+	buffer := &bufferedWriter{header: c.Writer.Header()}
+	if err := r.Render(buffer); err != nil {
+		c.Error(err)
+		if !c.Writer.Written() {
+			//撤销渲染器设置的Content-Type,以免影响错误响应
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.WriteHeader(http.StatusInternalServerError)
+		}
+		c.Abort()
+		return
+	}
+	c.Writer.Write(buffer.Bytes())
+}
+
 //构造文本类型响应
 func (c *Context) String(code int, format string, value ...interface{}) {
-	c.SetHeader("Content-Type", "text/plain")
-	c.SetStatus(code)
-	c.Writer.Write([]byte(fmt.Sprintf(format, value...)))
+	c.Render(code, render.String{Format: format, Data: value})
 }
 
 //构造JSON类型响应
 func (c *Context) JSON(code int, obj interface{}) {
-	c.SetHeader("Content-Type", "application/json")
-	c.SetStatus(code)
-	encoder := json.NewEncoder(c.Writer)
-	if err := encoder.Encode(obj); err != nil {
-		panic(err) //JSON编码失败,则引发错误
+	c.Render(code, render.JSON{Data: obj})
+}
+
+//构造带缩进的JSON类型响应,便于阅读但占用更多带宽
+func (c *Context) IndentedJSON(code int, obj interface{}) {
+	c.Render(code, render.IndentedJSON{Data: obj})
+}
+
+//构造安全JSON类型响应,数组数据前添加前缀以防止JSON劫持
+func (c *Context) SecureJSON(code int, obj interface{}) {
+	c.Render(code, render.SecureJSON{Prefix: c.engine.secureJSONPrefix, Data: obj})
+}
+
+//构造JSONP类型响应,回调函数名取自查询参数callback,不合法时退回普通JSON
+func (c *Context) JSONP(code int, obj interface{}) {
+	callback := c.Query("callback")
+	if callback == "" {
+		c.Render(code, render.JSON{Data: obj})
+		return
+	}
+	if !render.ValidJSONPCallback(callback) {
+		//不合法的回调函数名可能用于注入脚本,退回普通JSON
+		c.Error(render.ErrInvalidJSONPCallback)
+		c.Render(code, render.JSON{Data: obj})
+		return
 	}
+	c.Render(code, render.JsonpJSON{Callback: callback, Data: obj})
+}
+
+//构造ASCII JSON类型响应,非ASCII字符被转义
+func (c *Context) AsciiJSON(code int, obj interface{}) {
+	c.Render(code, render.AsciiJSON{Data: obj})
+}
+
+//构造XML类型响应
+func (c *Context) XML(code int, obj interface{}) {
+	c.Render(code, render.XML{Data: obj})
+}
+
+//构造YAML类型响应
+func (c *Context) YAML(code int, obj interface{}) {
+	c.Render(code, render.YAML{Data: obj})
+}
+
+//构造TOML类型响应
+func (c *Context) TOML(code int, obj interface{}) {
+	c.Render(code, render.TOML{Data: obj})
+}
+
+//构造ProtoBuf类型响应,obj需实现proto.Message接口
+func (c *Context) ProtoBuf(code int, obj interface{}) {
+	c.Render(code, render.ProtoBuf{Data: obj})
+}
+
+//构造MsgPack类型响应
+func (c *Context) MsgPack(code int, obj interface{}) {
+	c.Render(code, render.MsgPack{Data: obj})
 }
 
 //构造普通数据响应
 func (c *Context) Data(code int, data []byte) {
-	c.SetStatus(code)
-	c.Writer.Write(data)
+	c.Render(code, render.Data{Data: data})
 }
 
 //构造超文本类型响应并进行渲染
+//使用单一模板集合时name为模板名,使用多模板集合时name为模板集合名
 func (c *Context) HTML(code int, name string, data interface{}) {
-	c.SetHeader("Content-Type", "text/html")
-	c.SetStatus(code)
-	//执行模板渲染
-	if err := c.engine.htmlTemplates.ExecuteTemplate(c.Writer, name, data); err != nil {
-		panic(err) //HTML模板渲染失败,则引发错误
+	htmlRender := c.htmlRender
+	if htmlRender == nil {
+		htmlRender = render.HTMLProduction{} //未加载模板,渲染时返回错误
+	}
+	c.Render(code, htmlRender.Instance(name, data))
+}
+
+//构造服务器推送事件响应,事件ID和重连间隔可通过Render(code, render.SSEvent{...})设置
+func (c *Context) SSEvent(name string, message interface{}) {
+	c.Render(c.Writer.Status(), render.SSEvent{Event: name, Data: message})
+}
+
+//流式输出响应,重复调用step并在每次调用后刷新,step返回false时结束
+//客户端断开连接时提前结束并返回true
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	w := c.Writer
+	clientGone := c.Request.Context().Done()
+	for {
+		select {
+		case <-clientGone:
+			return true
+		default:
+			keepOpen := step(w)
+			w.Flush()
+			if !keepOpen {
+				return false
+			}
+		}
+	}
+}
+
+//立即将已写入的数据刷新到客户端
+func (c *Context) Flush() {
+	c.Writer.Flush()
+}
+
+//从reader流式读取数据构造响应,headers为额外的响应首部,不覆盖已设置的同名首部
+//contentLength小于0时不设置Content-Length;
+//仅当reader实现io.ReadSeeker且code为200时支持Range和If-Modified-Since请求,
+//此时contentLength不小于0则只发送reader当前位置起的contentLength字节
+func (c *Context) DataFromReader(code int, contentLength int64, contentType string,
+	reader io.Reader, headers map[string]string) {
+	seeker, ok := reader.(io.ReadSeeker)
+	if !ok || code != http.StatusOK {
+		c.Render(code, render.Reader{
+			ContentType:   contentType,
+			ContentLength: contentLength,
+			Reader:        reader,
+			Headers:       headers,
+		})
+		return
+	}
+	//与render.Reader相同,Content-Type和额外首部均不覆盖已设置的值
+	header := c.Writer.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", contentType)
+	}
+	for k, v := range headers {
+		if header.Get(k) == "" {
+			header.Set(k, v)
+		}
+	}
+	if contentLength >= 0 {
+		base, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			c.Error(err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		seeker = &sectionSeeker{r: seeker, base: base, size: contentLength}
+	}
+	//根据Last-Modified首部确定修改时间,用于处理条件请求
+	modtime, _ := http.ParseTime(header.Get("Last-Modified"))
+	http.ServeContent(c.Writer, c.Request, "", modtime, seeker)
+}
+
+//将io.ReadSeeker限制为从base开始的size字节
+type sectionSeeker struct {
+	r    io.ReadSeeker //原始数据
+	base int64         //起始位置
+	size int64         //长度
+	off  int64         //相对base的当前位置
+}
+
+//读取数据,不超过size
+func (s *sectionSeeker) Read(p []byte) (int, error) {
+	if s.off >= s.size {
+		return 0, io.EOF
+	}
+	if remaining := s.size - s.off; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := s.r.Read(p)
+	s.off += int64(n)
+	return n, err
+}
+
+//在[0, size]范围内移动读取位置
+func (s *sectionSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += s.off
+	case io.SeekEnd:
+		offset += s.size
+	}
+	if offset < 0 {
+		return 0, errors.New("gee: negative seek position")
+	}
+	if offset > s.size {
+		offset = s.size
+	}
+	if _, err := s.r.Seek(s.base+offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	s.off = offset
+	return offset, nil
+}
+
+//以文件内容构造响应,支持Range和If-Modified-Since请求
+func (c *Context) File(filepath string) {
+	http.ServeFile(c.Writer, c.Request, filepath)
+}
+
+//以附件形式返回文件,浏览器将以filename为名下载
+func (c *Context) FileAttachment(filepath, filename string) {
+	c.SetHeader("Content-Disposition",
+		mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	http.ServeFile(c.Writer, c.Request, filepath)
+}
+
+//以文件系统fs中的文件内容构造响应
+func (c *Context) FileFromFS(filepath string, fs http.FileSystem) {
+	//文件服务器按请求的URL路径查找文件,处理完毕后恢复原路径
+	defer func(old string) {
+		c.Request.URL.Path = old
+	}(c.Request.URL.Path)
+	c.Request.URL.Path = filepath
+	http.FileServer(fs).ServeHTTP(c.Writer, c.Request)
+}
+
+//判断该状态码的响应是否允许包含响应体
+func bodyAllowedForStatus(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == http.StatusNoContent:
+		return false
+	case status == http.StatusNotModified:
+		return false
 	}
+	return true
 }
@@ -0,0 +1,138 @@
+package gee
+
+//跨域资源共享中间件部分
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//跨域资源共享中间件配置
+type CORSConfig struct {
+	//允许的源,"*"表示允许所有源,可包含一个通配符,如"https://*.example.com"
+	AllowOrigins []string
+	//判断源是否允许的函数,与AllowOrigins任一匹配即允许
+	AllowOriginFunc func(origin string) bool
+	//允许的请求方法,为空时允许GET、POST、PUT、PATCH、DELETE和HEAD
+	AllowMethods []string
+	//允许的请求首部,为空时允许预检请求中声明的所有首部
+	AllowHeaders []string
+	//允许浏览器脚本读取的响应首部
+	ExposeHeaders []string
+	//是否允许携带Cookie等凭据,此时不能允许所有源
+	AllowCredentials bool
+	//预检结果的缓存时间,为0时不设置
+	MaxAge time.Duration
+}
+
+//默认允许的请求方法
+var defaultCORSMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodHead,
+}
+
+//返回允许所有源的跨域资源共享中间件函数
+func CORS() HandlerFunc {
+	return CORSWithConfig(CORSConfig{AllowOrigins: []string{"*"}})
+}
+
+//按配置返回跨域资源共享中间件函数
+//中间件直接响应预检请求,因此无需注册OPTIONS路由,但需通过Use添加到覆盖请求路径的分组
+func CORSWithConfig(config CORSConfig) HandlerFunc {
+	allowAll := false
+	var exact []string
+	var wildcards [][2]string
+	for _, origin := range config.AllowOrigins {
+		origin = strings.ToLower(origin)
+		if origin == "*" {
+			allowAll = true
+		} else if prefix, suffix, found := strings.Cut(origin, "*"); found {
+			wildcards = append(wildcards, [2]string{prefix, suffix})
+		} else {
+			exact = append(exact, origin)
+		}
+	}
+	if allowAll && config.AllowCredentials {
+		panic("cors: all origins can not be allowed when credentials are allowed")
+	}
+	if !allowAll && len(exact) == 0 && len(wildcards) == 0 && config.AllowOriginFunc == nil {
+		panic("cors: no origins are allowed")
+	}
+	if len(config.AllowMethods) == 0 {
+		config.AllowMethods = defaultCORSMethods
+	}
+	allowMethods := strings.Join(config.AllowMethods, ", ")
+	allowHeaders := strings.Join(config.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(config.ExposeHeaders, ", ")
+	maxAge := strconv.Itoa(int(config.MaxAge / time.Second))
+
+	//判断源是否允许
+	allowed := func(origin string) bool {
+		if allowAll {
+			return true
+		}
+		lower := strings.ToLower(origin)
+		for _, o := range exact {
+			if lower == o {
+				return true
+			}
+		}
+		for _, w := range wildcards {
+			if len(lower) >= len(w[0])+len(w[1]) &&
+				strings.HasPrefix(lower, w[0]) && strings.HasSuffix(lower, w[1]) {
+				return true
+			}
+		}
+		return config.AllowOriginFunc != nil && config.AllowOriginFunc(origin)
+	}
+
+	return func(c *Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next() //非跨域请求
+			return
+		}
+		header := c.Writer.Header()
+		preflight := c.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if !allowAll {
+			header.Add("Vary", "Origin") //响应随源变化,避免缓存返回给其他源
+		}
+		if !allowed(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next() //不添加CORS首部,由浏览器阻止脚本读取响应
+			return
+		}
+		if allowAll {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if config.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if !preflight {
+			if exposeHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+			c.Next()
+			return
+		}
+		//预检请求
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+		header.Set("Access-Control-Allow-Methods", allowMethods)
+		if allowHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", allowHeaders)
+		} else if requested := c.GetHeader("Access-Control-Request-Headers"); requested != "" {
+			header.Set("Access-Control-Allow-Headers", requested)
+		}
+		if config.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", maxAge)
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,97 @@
+package gee
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	r := New()
+	r.Use(CORSWithConfig(CORSConfig{
+		AllowOrigins: []string{"https://example.com", "https://*.example.org"},
+		MaxAge:       time.Hour,
+	}))
+	r.GET("/data", func(c *Context) { c.String(200, "ok") })
+
+	tests := []struct {
+		name       string
+		method     string
+		origin     string
+		preflight  bool
+		wantCode   int
+		wantOrigin string
+	}{
+		{"精确匹配", "GET", "https://example.com", false, 200, "https://example.com"},
+		{"通配符匹配", "GET", "https://api.example.org", false, 200, "https://api.example.org"},
+		{"通配符不匹配裸域", "GET", "https://example.org", false, 200, ""},
+		{"通配符不匹配其他域", "GET", "https://api.example.org.evil.com", false, 200, ""},
+		{"非跨域请求", "GET", "", false, 200, ""},
+		//未注册OPTIONS路由时由中间件直接响应预检请求
+		{"允许的预检请求", "OPTIONS", "https://a.example.org", true, 204, "https://a.example.org"},
+		{"不允许的预检请求", "OPTIONS", "https://evil.com", true, 403, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/data", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if tt.preflight {
+			req.Header.Set("Access-Control-Request-Method", "GET")
+			req.Header.Set("Access-Control-Request-Headers", "X-Token")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.wantCode {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.wantCode)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+			t.Errorf("%s: Access-Control-Allow-Origin = %q, want %q", tt.name, got, tt.wantOrigin)
+		}
+		if tt.preflight && tt.wantCode == 204 {
+			if got := w.Header().Get("Access-Control-Allow-Headers"); got != "X-Token" {
+				t.Errorf("%s: Access-Control-Allow-Headers = %q", tt.name, got)
+			}
+			if got := w.Header().Get("Access-Control-Max-Age"); got != "3600" {
+				t.Errorf("%s: Access-Control-Max-Age = %q", tt.name, got)
+			}
+		}
+	}
+}
+
+func TestCORSAllowAll(t *testing.T) {
+	r := New()
+	r.Use(CORS())
+	r.GET("/data", func(c *Context) { c.String(200, "ok") })
+	req := httptest.NewRequest("GET", "/data", nil)
+	req.Header.Set("Origin", "https://any.com")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Fatalf("Access-Control-Allow-Origin = %q, want *", got)
+	}
+	if w.Header().Get("Vary") != "" {
+		t.Fatalf("unexpected Vary: %q", w.Header().Get("Vary"))
+	}
+}
+
+func TestCORSConfigPanics(t *testing.T) {
+	tests := []struct {
+		name   string
+		config CORSConfig
+	}{
+		{"允许凭据时不能允许所有源", CORSConfig{AllowOrigins: []string{"*"}, AllowCredentials: true}},
+		{"未允许任何源", CORSConfig{}},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected panic", tt.name)
+				}
+			}()
+			CORSWithConfig(tt.config)
+		}()
+	}
+}
+
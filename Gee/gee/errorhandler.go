@@ -0,0 +1,62 @@
+package gee
+
+//错误处理中间件部分
+import (
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+//返回错误处理中间件函数
+//在后续处理函数执行完毕后,将上下文中记录的错误统一渲染为响应
+func ErrorHandler() HandlerFunc {
+	return func(c *Context) {
+		c.Next()
+		//无错误或响应已写出时不再处理
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		code := errorStatus(c)
+		//私有错误不向客户端展示具体信息和元数据
+		messages := make([]string, 0, len(c.Errors))
+		details := make([]interface{}, 0, len(c.Errors))
+		for _, e := range c.Errors {
+			if e.IsType(ErrorTypePublic | ErrorTypeBind) {
+				messages = append(messages, e.Error())
+				details = append(details, e.JSON())
+			} else {
+				messages = append(messages, http.StatusText(code))
+				details = append(details, H{"error": http.StatusText(code)})
+			}
+		}
+		//根据内容协商选择输出格式,默认为JSON
+		if c.NegotiateFormat(MIMEJSON, MIMEHTML) == MIMEHTML {
+			var body strings.Builder
+			body.WriteString("<html><body><h1>")
+			body.WriteString(http.StatusText(code))
+			body.WriteString("</h1><ul>")
+			for _, message := range messages {
+				body.WriteString("<li>")
+				body.WriteString(template.HTMLEscapeString(message))
+				body.WriteString("</li>")
+			}
+			body.WriteString("</ul></body></html>")
+			c.SetHeader("Content-Type", "text/html")
+			c.Data(code, []byte(body.String()))
+			return
+		}
+		c.JSON(code, H{"errors": details})
+	}
+}
+
+//根据记录的错误确定响应状态码
+//已设置错误状态码(如AbortWithError)时沿用,全部为绑定错误时返回400,否则返回500
+func errorStatus(c *Context) int {
+	if status := c.Writer.Status(); status >= http.StatusBadRequest {
+		return status
+	}
+	if len(c.Errors.ByType(ErrorTypeBind)) == len(c.Errors) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,141 @@
+package gee
+
+//错误记录部分
+import (
+	"fmt"
+	"strings"
+)
+
+//错误类型,使用位标记以便按类型组合筛选
+type ErrorType uint64
+
+const (
+	ErrorTypeBind    ErrorType = 1 << 63   //请求参数绑定错误
+	ErrorTypePrivate ErrorType = 1 << 0    //私有错误,不向客户端展示具体信息
+	ErrorTypePublic  ErrorType = 1 << 1    //公开错误,可向客户端展示信息
+	ErrorTypeAny     ErrorType = 1<<64 - 1 //任意类型
+)
+
+//处理过程中记录的错误
+type Error struct {
+	Err  error       //原始错误
+	Type ErrorType   //错误类型
+	Meta interface{} //附加的元数据
+}
+
+//错误列表
+type errorMsgs []*Error
+
+//实现error接口
+func (msg *Error) Error() string {
+	return msg.Err.Error()
+}
+
+//设置错误类型,返回自身以便链式调用
+func (msg *Error) SetType(flags ErrorType) *Error {
+	msg.Type = flags
+	return msg
+}
+
+//设置错误元数据,返回自身以便链式调用
+func (msg *Error) SetMeta(data interface{}) *Error {
+	msg.Meta = data
+	return msg
+}
+
+//判断错误是否属于给定类型
+func (msg *Error) IsType(flags ErrorType) bool {
+	return (msg.Type & flags) > 0
+}
+
+//返回用于JSON输出的数据
+func (msg *Error) JSON() interface{} {
+	jsonData := H{}
+	if msg.Meta != nil {
+		//元数据为H时合并到输出中,否则作为meta字段
+		if meta, ok := msg.Meta.(H); ok {
+			for key, value := range meta {
+				jsonData[key] = value
+			}
+		} else {
+			jsonData["meta"] = msg.Meta
+		}
+	}
+	if _, ok := jsonData["error"]; !ok {
+		jsonData["error"] = msg.Error()
+	}
+	return jsonData
+}
+
+//支持errors.Unwrap
+func (msg *Error) Unwrap() error {
+	return msg.Err
+}
+
+//按类型筛选错误
+func (a errorMsgs) ByType(typ ErrorType) errorMsgs {
+	if len(a) == 0 {
+		return nil
+	}
+	if typ == ErrorTypeAny {
+		return a
+	}
+	var result errorMsgs
+	for _, msg := range a {
+		if msg.IsType(typ) {
+			result = append(result, msg)
+		}
+	}
+	return result
+}
+
+//返回最后一个错误,无错误则返回nil
+func (a errorMsgs) Last() *Error {
+	if length := len(a); length > 0 {
+		return a[length-1]
+	}
+	return nil
+}
+
+//返回所有错误信息字符串
+func (a errorMsgs) Errors() []string {
+	if len(a) == 0 {
+		return nil
+	}
+	errorStrings := make([]string, len(a))
+	for i, msg := range a {
+		errorStrings[i] = msg.Error()
+	}
+	return errorStrings
+}
+
+//返回用于JSON输出的数据,单个错误时不包装为数组
+func (a errorMsgs) JSON() interface{} {
+	switch length := len(a); length {
+	case 0:
+		return nil
+	case 1:
+		return a.Last().JSON()
+	default:
+		jsonData := make([]interface{}, length)
+		for i, msg := range a {
+			jsonData[i] = msg.JSON()
+		}
+		return jsonData
+	}
+}
+
+//转换字符串输出
+func (a errorMsgs) String() string {
+	if len(a) == 0 {
+		return ""
+	}
+	var buffer strings.Builder
+	for i, msg := range a {
+		fmt.Fprintf(&buffer, "Error #%02d: %s\n", i+1, msg.Err)
+		if msg.Meta != nil {
+			fmt.Fprintf(&buffer, "     Meta: %v\n", msg.Meta)
+		}
+	}
+	return buffer.String()
+}
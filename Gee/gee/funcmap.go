@@ -0,0 +1,162 @@
+package gee
+
+//内置模板函数部分
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/url"
+	"strings"
+	"time"
+)
+
+//date函数可使用的具名时间格式,也可直接使用time包的格式字符串
+var dateLayouts = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"RFC822":      time.RFC822,
+	"RFC1123":     time.RFC1123,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"DateTime":    time.DateTime,
+	"DateOnly":    time.DateOnly,
+	"TimeOnly":    time.TimeOnly,
+}
+
+//返回内置的模板函数,不含依赖Engine的url函数
+//date: 格式化时间,默认格式为"2006-01-02",如{{date .now "DateTime"}}
+//now: 当前时间
+//safeHTML、safeJS、safeURL: 将字符串标记为可信内容,不再转义
+//dict、list: 构造映射和列表,如{{template "item" dict "name" .Name "id" .ID}}
+//upper、lower、trim、trimPrefix、trimSuffix、contains、hasPrefix、hasSuffix、
+//replace、split、join、repeat、truncate: 字符串函数,参数顺序便于在管道中使用
+//json: 编码为JSON,在<script>中作为JS表达式输出,如var cfg = {{json .}};在HTML文本和属性中按普通文本转义
+func DefaultFuncMap() template.FuncMap {
+	return template.FuncMap{
+		"date":       formatDate,
+		"now":        time.Now,
+		"safeHTML":   func(s string) template.HTML { return template.HTML(s) },
+		"safeJS":     func(s string) template.JS { return template.JS(s) },
+		"safeURL":    func(s string) template.URL { return template.URL(s) },
+		"dict":       dict,
+		"list":       func(items ...interface{}) []interface{} { return items },
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":       func(sep string, elems []string) string { return strings.Join(elems, sep) },
+		"repeat":     func(count int, s string) string { return strings.Repeat(s, count) },
+		"truncate":   truncate,
+		"json":       toJSON,
+	}
+}
+
+//格式化时间,layout可为具名格式或格式字符串,省略时为"2006-01-02"
+func formatDate(t time.Time, layout ...string) string {
+	if len(layout) == 0 {
+		return t.Format(time.DateOnly)
+	}
+	if named, ok := dateLayouts[layout[0]]; ok {
+		return t.Format(named)
+	}
+	return t.Format(layout[0])
+}
+
+//由交替的键和值构造映射
+func dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("dict: odd number of arguments")
+	}
+	m := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict: key %v is not a string", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
+
+//截断字符串至最多n个字符,截断时以"..."结尾
+func truncate(n int, s string) string {
+	runes := []rune(s)
+	if n < 0 || len(runes) <= n {
+		return s
+	}
+	if n <= 3 {
+		return string(runes[:n])
+	}
+	return string(runes[:n-3]) + "..."
+}
+
+//编码为JSON,json.Marshal会转义<、>和&,因此可安全地嵌入<script>
+func toJSON(v interface{}) (template.JS, error) {
+	data, err := json.Marshal(v)
+	return template.JS(data), err
+}
+
+//启用内置模板函数,与SetFuncMap设置的函数合并,同名时SetFuncMap设置的函数优先
+//除DefaultFuncMap中的函数外,还提供url函数由路由模式生成路径,
+//如{{url "/user/:name/*filepath" "geek" "a/b.txt"}}生成"/user/geek/a/b.txt",路由未注册时返回错误
+func (engine *Engine) UseDefaultFuncMap() error {
+	engine.htmlLoadMu.Lock()
+	defer engine.htmlLoadMu.Unlock()
+	engine.htmlMu.Lock()
+	engine.defaultFuncs = true
+	engine.htmlMu.Unlock()
+	return engine.reloadHTML()
+}
+
+//合并内置模板函数和自定义模板函数,调用时需持有htmlMu
+func (engine *Engine) mergeFuncMap(funcMap template.FuncMap) template.FuncMap {
+	if !engine.defaultFuncs {
+		return funcMap
+	}
+	merged := DefaultFuncMap()
+	merged["url"] = engine.routeURL
+	for name, fn := range funcMap {
+		merged[name] = fn
+	}
+	return merged
+}
+
+//由路由模式pattern和按顺序对应动态参数的params生成路径
+func (engine *Engine) routeURL(pattern string, params ...interface{}) (string, error) {
+	if !engine.router.hasPattern(pattern) {
+		return "", fmt.Errorf("url: route %q is not registered", pattern)
+	}
+	parts := strings.Split(pattern, "/")
+	for i, part := range parts {
+		if part == "" || (part[0] != ':' && part[0] != '*') {
+			continue
+		}
+		if len(params) == 0 {
+			return "", fmt.Errorf("url: missing value for %q in route %q", part, pattern)
+		}
+		value := fmt.Sprint(params[0])
+		params = params[1:]
+		if part[0] == '*' {
+			//通配参数可包含多级路径,逐级转义
+			segments := strings.Split(value, "/")
+			for j, segment := range segments {
+				segments[j] = url.PathEscape(segment)
+			}
+			parts[i] = strings.Join(segments, "/")
+			parts = parts[:i+1]
+			break
+		}
+		parts[i] = url.PathEscape(value)
+	}
+	if len(params) > 0 {
+		return "", fmt.Errorf("url: too many values for route %q", pattern)
+	}
+	return strings.Join(parts, "/"), nil
+}
@@ -2,9 +2,13 @@ package gee
 
 //框架主体部分
 import (
+	"gee/render"
 	"html/template"
+	"io/fs"
+	"log/slog"
 	"net/http"
 	"strings"
+	"sync"
 )
 
 //处理函数
@@ -12,17 +16,24 @@ type HandlerFunc func(c *Context)
 
 //框架主体结构体
 type Engine struct {
-	*RouterGroup                     //默认的路由分组,未分组的路由都加入该分组
-	router        *router            //路由
-	groups        []*RouterGroup     //存储所有路由分组
-	htmlTemplates *template.Template //解析后的模板对象指针
-	funcMap       template.FuncMap   //自定义模板渲染函数映射表
+	*RouterGroup                             //默认的路由分组,未分组的路由都加入该分组
+	router           *router                 //路由
+	groups           []*RouterGroup          //存储所有路由分组
+	htmlLoadMu       sync.Mutex              //串行化模板的加载和重新解析,保证最后设置的函数生效
+	htmlMu           sync.RWMutex            //保护模板相关配置,使其可在服务过程中修改
+	userFuncMap      template.FuncMap        //自定义模板渲染函数映射表
+	defaultFuncs     bool                    //是否启用内置模板函数
+	htmlTemplates    []*render.HTMLTemplates //由NewHTMLTemplates创建的多模板集合
+	secureJSONPrefix string                  //SecureJSON中数组数据的前缀
+	noRoute          []HandlerFunc           //未匹配路由时的处理函数集
+	logger           *slog.Logger            //框架的结构化日志记录器,为nil时使用log包
 }
 
 //Engine构造函数
 func New() *Engine {
 	engine := &Engine{
-		router: newRouter(),
+		router:           newRouter(),
+		secureJSONPrefix: "while(1);",
 	}
 	//构建根路由分组,其engine指向框架主体
 	engine.RouterGroup = &RouterGroup{
@@ -40,17 +51,116 @@ func Default() *Engine {
 	return engine
 }
 
-//添加自定义模板渲染函数
-func (engine *Engine) SetFuncMap(funcMap template.FuncMap) {
-	engine.funcMap = funcMap
+//设置未匹配路由时的处理函数,未设置时返回默认的404响应
+func (engine *Engine) NoRoute(handlers ...HandlerFunc) {
+	engine.noRoute = handlers
 }
 
-//加载HTML模板
-func (engine *Engine) LoadHTMLGlob(pattern string) {
-	engine.htmlTemplates = template.Must( //模板初始化
-		template.New(""). //新建匿名模板
-			Funcs(engine.funcMap). //加载添加的自定义函数
-			ParseGlob(pattern)) //解析模板文件
+//返回未匹配路由时的处理函数集
+func (engine *Engine) noRouteHandlers() []HandlerFunc {
+	if len(engine.noRoute) == 0 {
+		return []HandlerFunc{notFoundHandler}
+	}
+	return engine.noRoute
+}
+
+//默认的404处理函数
+func notFoundHandler(c *Context) {
+	c.String(http.StatusNotFound, "404 NOT FOUND :%s\n", c.Path)
+}
+
+//设置自定义模板渲染函数,已加载的模板及由NewHTMLTemplates创建的多模板集合以新的函数重新解析
+//可在加载模板之前或之后调用,也可在服务过程中调用,解析失败时保留原有模板并返回错误
+//通过UseDefaultFuncMap启用内置模板函数后,funcMap与内置函数合并,同名时funcMap中的函数优先
+func (engine *Engine) SetFuncMap(funcMap template.FuncMap) error {
+	engine.htmlLoadMu.Lock()
+	defer engine.htmlLoadMu.Unlock()
+	engine.htmlMu.Lock()
+	engine.userFuncMap = funcMap
+	engine.htmlMu.Unlock()
+	return engine.reloadHTML()
+}
+
+//以当前的模板渲染函数重新解析所有模板,返回遇到的第一个错误,调用时需持有htmlLoadMu
+func (engine *Engine) reloadHTML() error {
+	engine.htmlMu.Lock()
+	funcMap := engine.mergeFuncMap(engine.userFuncMap)
+	setters := make(map[htmlFuncMapSetter]struct{})
+	for _, templates := range engine.htmlTemplates {
+		setters[templates] = struct{}{}
+	}
+	for _, group := range engine.groups {
+		if setter, ok := group.htmlRender.(htmlFuncMapSetter); ok {
+			setters[setter] = struct{}{}
+		}
+	}
+	engine.htmlMu.Unlock()
+	//重新解析在htmlMu之外进行,解析期间的请求仍使用原有模板
+	var firstErr error
+	for setter := range setters {
+		if err := setter.SetFuncMap(funcMap); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+//可以新的自定义函数重新解析模板的HTML渲染器
+type htmlFuncMapSetter interface {
+	SetFuncMap(funcMap template.FuncMap) error
+}
+
+var (
+	_ htmlFuncMapSetter = (*render.HTMLLoader)(nil)
+	_ htmlFuncMapSetter = (*render.HTMLTemplates)(nil)
+	_ htmlFuncMapSetter = (*render.HTMLDebug)(nil)
+)
+
+//设置SecureJSON中数组数据的前缀
+func (engine *Engine) SecureJsonPrefix(prefix string) *Engine {
+	engine.secureJSONPrefix = prefix
+	return engine
+}
+
+//设置已解析的HTML模板集合
+func (engine *Engine) SetHTMLTemplate(tmpl *template.Template) {
+	engine.SetHTMLRender(render.HTMLProduction{Template: tmpl})
+}
+
+//创建使用自定义模板渲染函数的多模板集合,添加模板集合后通过SetHTMLRender设置
+//调试模式下模板集合在模板文件修改后自动重新解析
+func (engine *Engine) NewHTMLTemplates() *render.HTMLTemplates {
+	engine.htmlMu.Lock()
+	defer engine.htmlMu.Unlock()
+	templates := render.NewHTMLTemplates(engine.mergeFuncMap(engine.userFuncMap))
+	templates.Debug = IsDebugging()
+	//记录多模板集合,以便SetFuncMap时重新解析
+	engine.htmlTemplates = append(engine.htmlTemplates, templates)
+	return templates
+}
+
+//由模板来源加载HTML模板并返回解析错误,调试模式下模板文件修改后自动重新解析
+//解析失败时仍记录模板来源,渲染时返回该错误,之后调用SetFuncMap会以新的函数重新解析
+func (engine *Engine) loadHTML(source render.HTMLSource) error {
+	engine.htmlLoadMu.Lock()
+	defer engine.htmlLoadMu.Unlock()
+	engine.htmlMu.RLock()
+	funcMap := engine.mergeFuncMap(engine.userFuncMap)
+	engine.htmlMu.RUnlock()
+	loader := render.NewHTMLLoader(source, IsDebugging())
+	err := loader.SetFuncMap(funcMap)
+	engine.SetHTMLRender(loader)
+	return err
+}
+
+//加载匹配pattern的HTML模板
+func (engine *Engine) LoadHTMLGlob(pattern string) error {
+	return engine.loadHTML(render.HTMLSource{Glob: pattern})
+}
+
+//从文件系统fsys加载HTML模板,可用于go:embed嵌入的模板
+func (engine *Engine) LoadHTMLFS(fsys fs.FS, patterns ...string) error {
+	return engine.loadHTML(render.HTMLSource{FS: fsys, Patterns: patterns})
 }
 
 //服务端http.Handler接口函数
@@ -61,10 +171,15 @@ func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		if c.Path == group.prefix || strings.HasPrefix(c.Path, group.prefix+"/") {
 			//添加中间件函数
 			c.handlers = append(c.handlers, group.middlewares...)
+			//子分组总是在父分组之后创建,因此最后匹配的即为最具体的分组
+			if htmlRender := group.getHTMLRender(); htmlRender != nil {
+				c.htmlRender = htmlRender
+			}
 		}
 	}
-	c.engine = engine       //初始化上下文的Engine指针
-	engine.router.handle(c) //执行路由处理
+	c.engine = engine         //初始化上下文的Engine指针
+	engine.router.handle(c)   //执行路由处理
+	c.Writer.WriteHeaderNow() //处理函数未写入响应体时,确保首部被写出
 }
 
 //运行框架
@@ -1,2 +1,12 @@
-module "gee"
-go 1.14
\ No newline at end of file
+module gee
+
+go 1.23
+
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/vmihailenco/msgpack/v5 v5.4.1
+	google.golang.org/protobuf v1.36.12
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
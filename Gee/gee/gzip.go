@@ -0,0 +1,288 @@
+package gee
+
+//响应压缩中间件部分
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+//小于该长度的响应体默认不压缩
+const DefaultGzipMinLength = 1024
+
+//压缩中间件配置
+type GzipConfig struct {
+	Level                int      //压缩级别,取值同compress/gzip,为0时使用默认级别
+	MinLength            int      //响应体小于该长度时不压缩,为0时总是压缩
+	ExcludedExtensions   []string //不压缩的请求路径后缀,如".png"
+	ExcludedPaths        []string //不压缩的请求路径前缀
+	ExcludedContentTypes []string //不压缩的Content-Type前缀,如已压缩的图片视频
+}
+
+//默认不压缩的Content-Type,这些格式本身已经过压缩
+var defaultExcludedContentTypes = []string{
+	"image/", "video/", "audio/", "font/woff",
+	"application/zip", "application/gzip", "application/x-gzip",
+	"application/x-brotli", "application/octet-stream",
+}
+
+//返回指定压缩级别的压缩中间件函数
+//level取值同compress/gzip,由于gzip.NoCompression为0,传入0时使用默认级别而非不压缩
+func Gzip(level int) HandlerFunc {
+	return GzipWithConfig(GzipConfig{
+		Level:                level,
+		MinLength:            DefaultGzipMinLength,
+		ExcludedContentTypes: defaultExcludedContentTypes,
+	})
+}
+
+//按配置返回压缩中间件函数
+//根据Accept-Encoding选择gzip或deflate编码,响应已设置Content-Encoding时不再压缩
+func GzipWithConfig(config GzipConfig) HandlerFunc {
+	if config.Level == 0 {
+		config.Level = gzip.DefaultCompression
+	}
+	if config.Level < gzip.HuffmanOnly || config.Level > gzip.BestCompression {
+		panic("gzip: invalid compression level")
+	}
+	pools := newCompressorPools(config.Level)
+	return func(c *Context) {
+		encoding := negotiateEncoding(c.Request)
+		if encoding == "" || config.isExcluded(c.Request) {
+			c.Next()
+			return
+		}
+		w := &compressWriter{
+			ResponseWriter: c.Writer,
+			config:         &config,
+			pools:          pools,
+			encoding:       encoding,
+		}
+		c.Writer = w
+		defer func() {
+			w.close()
+			c.Writer = w.ResponseWriter //恢复原始响应,外层中间件直接写入
+		}()
+		c.Next()
+	}
+}
+
+//判断请求是否被排除在压缩之外
+func (config *GzipConfig) isExcluded(req *http.Request) bool {
+	//WebSocket等协议升级请求不压缩
+	if headerContainsToken(req.Header, "Connection", "upgrade") {
+		return true
+	}
+	ext := path.Ext(req.URL.Path)
+	for _, excluded := range config.ExcludedExtensions {
+		if ext == excluded {
+			return true
+		}
+	}
+	for _, prefix := range config.ExcludedPaths {
+		if strings.HasPrefix(req.URL.Path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+//判断Content-Type是否被排除在压缩之外
+func (config *GzipConfig) isExcludedContentType(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, prefix := range config.ExcludedContentTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+//根据Accept-Encoding选择压缩编码,q值相同时优先gzip,"*"视为gzip,均不可接受时返回空字符串
+func negotiateEncoding(req *http.Request) string {
+	var chosen string
+	var chosenQuality float64
+	for _, item := range parseAcceptItems(req.Header.Get("Accept-Encoding")) {
+		encoding := strings.ToLower(item.mime)
+		if encoding == "*" {
+			encoding = "gzip"
+		}
+		if encoding != "gzip" && encoding != "deflate" {
+			continue
+		}
+		if item.quality > chosenQuality || (item.quality == chosenQuality && encoding == "gzip") {
+			chosen, chosenQuality = encoding, item.quality
+		}
+	}
+	return chosen
+}
+
+//可重置的压缩器
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+//gzip和deflate压缩器的对象池,HTTP的deflate编码为zlib格式(RFC 9110 8.4.1.2)
+type compressorPools struct {
+	gzip    sync.Pool
+	deflate sync.Pool
+}
+
+//compressorPools构造函数
+func newCompressorPools(level int) *compressorPools {
+	pools := &compressorPools{}
+	pools.gzip.New = func() interface{} {
+		w, _ := gzip.NewWriterLevel(io.Discard, level)
+		return w
+	}
+	pools.deflate.New = func() interface{} {
+		w, _ := zlib.NewWriterLevel(io.Discard, level)
+		return w
+	}
+	return pools
+}
+
+//获取写入w的压缩器
+func (p *compressorPools) get(encoding string, w io.Writer) compressor {
+	var cw compressor
+	if encoding == "gzip" {
+		cw = p.gzip.Get().(*gzip.Writer)
+	} else {
+		cw = p.deflate.Get().(*zlib.Writer)
+	}
+	cw.Reset(w)
+	return cw
+}
+
+//归还压缩器
+func (p *compressorPools) put(encoding string, cw compressor) {
+	cw.Reset(io.Discard)
+	if encoding == "gzip" {
+		p.gzip.Put(cw)
+	} else {
+		p.deflate.Put(cw)
+	}
+}
+
+//压缩响应写入结构体
+//响应体先缓冲至MinLength再决定是否压缩,刷新时立即决定以支持流式响应
+type compressWriter struct {
+	ResponseWriter                  //原始响应
+	config         *GzipConfig      //压缩配置
+	pools          *compressorPools //压缩器对象池
+	encoding       string           //协商的压缩编码
+	buf            []byte           //决定是否压缩前缓冲的响应体
+	decided        bool             //是否已决定是否压缩
+	compressor     compressor       //压缩器,不压缩时为nil
+}
+
+//写入响应体
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, data...)
+		if len(w.buf) < w.config.MinLength {
+			return len(data), nil
+		}
+		if err := w.decide(false); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if w.compressor != nil {
+		return w.compressor.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+//写入字符串到响应体
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+//判断响应是否已写入,缓冲中的数据也视为已写入
+//仅决定了是否压缩而未写入任何数据时不算已写入,以便外层的错误恢复等中间件仍可构造响应
+func (w *compressWriter) Written() bool {
+	return len(w.buf) > 0 || w.compressor != nil || w.ResponseWriter.Written()
+}
+
+//立即写入响应首部
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.decided {
+		w.decide(false)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+//刷新已压缩的数据到客户端
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(true)
+	}
+	if w.compressor != nil {
+		w.compressor.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+//决定是否压缩并写出缓冲的数据,force为true时忽略最小长度限制
+func (w *compressWriter) decide(force bool) error {
+	w.decided = true
+	buf := w.buf
+	w.buf = nil
+	if w.shouldCompress(len(buf), force) {
+		header := w.Header()
+		if header.Get("Content-Type") == "" {
+			//压缩后无法再推断内容类型,因此在压缩前推断
+			header.Set("Content-Type", http.DetectContentType(buf))
+		}
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		w.compressor = w.pools.get(w.encoding, w.ResponseWriter)
+	}
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.compressor != nil {
+		_, err = w.compressor.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+//判断响应是否应当压缩
+func (w *compressWriter) shouldCompress(length int, force bool) bool {
+	header := w.Header()
+	//已编码、部分内容或不允许响应体的响应不压缩
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" ||
+		w.Status() == http.StatusPartialContent || !bodyAllowedForStatus(w.Status()) {
+		return false
+	}
+	if w.config.isExcludedContentType(header.Get("Content-Type")) {
+		return false
+	}
+	header.Add("Vary", "Accept-Encoding")
+	if force {
+		return true
+	}
+	return length > 0 && length >= w.config.MinLength
+}
+
+//结束压缩,写出缓冲的数据并归还压缩器
+func (w *compressWriter) close() {
+	if !w.decided {
+		w.decide(false)
+	}
+	if w.compressor != nil {
+		w.compressor.Close()
+		w.pools.put(w.encoding, w.compressor)
+		w.compressor = nil
+	}
+}
@@ -0,0 +1,101 @@
+package gee
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+//以给定的Accept-Encoding请求path
+func gzipRequest(r *Engine, path, encoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("Accept-Encoding", encoding)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestGzip(t *testing.T) {
+	large := strings.Repeat("gee gzip ", 200)
+	r := New()
+	r.Use(Gzip(gzip.DefaultCompression))
+	r.GET("/large", func(c *Context) { c.String(200, "%s", large) })
+	r.GET("/small", func(c *Context) { c.String(200, "small") })
+	r.GET("/sse", func(c *Context) {
+		c.Stream(func(w io.Writer) bool {
+			c.SSEvent("message", "hi")
+			return false
+		})
+	})
+
+	for encoding, newReader := range map[string]func(io.Reader) (io.Reader, error){
+		"gzip":    func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"deflate": func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) },
+	} {
+		w := gzipRequest(r, "/large", encoding)
+		if got := w.Header().Get("Content-Encoding"); got != encoding {
+			t.Fatalf("%s: Content-Encoding = %q", encoding, got)
+		}
+		zr, err := newReader(w.Body)
+		if err != nil {
+			t.Fatalf("%s: %v", encoding, err)
+		}
+		body, err := io.ReadAll(zr)
+		if err != nil || string(body) != large {
+			t.Fatalf("%s: decoded body mismatched, err = %v", encoding, err)
+		}
+	}
+
+	//小于MinLength的响应不压缩
+	w := gzipRequest(r, "/small", "gzip")
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != "small" {
+		t.Fatalf("small response: encoding %q, body %q", w.Header().Get("Content-Encoding"), w.Body.String())
+	}
+
+	//刷新时立即压缩,即使未达到MinLength
+	w = gzipRequest(r, "/sse", "gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("sse: Content-Encoding = %q", w.Header().Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(zr)
+	if !strings.Contains(string(body), "data:hi") {
+		t.Fatalf("sse: decoded body %q", body)
+	}
+}
+
+func TestGzipAcceptAny(t *testing.T) {
+	r := New()
+	r.Use(Gzip(gzip.DefaultCompression))
+	r.GET("/", func(c *Context) { c.String(200, "%s", strings.Repeat("a", 2048)) })
+	if w := gzipRequest(r, "/", "*"); w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Accept-Encoding *: Content-Encoding = %q", w.Header().Get("Content-Encoding"))
+	}
+}
+
+//Gzip内部的panic和错误仍由外层的Recovery和ErrorHandler构造响应
+func TestGzipOuterMiddlewares(t *testing.T) {
+	r := New()
+	r.Use(Recovery(), ErrorHandler(), Gzip(gzip.DefaultCompression))
+	r.GET("/panic", func(c *Context) { panic("boom") })
+	r.GET("/error", func(c *Context) {
+		c.AbortWithError(400, errors.New("bad input"))
+		c.Errors.Last().SetType(ErrorTypePublic)
+	})
+
+	w := gzipRequest(r, "/panic", "gzip")
+	if w.Code != 500 || !strings.Contains(w.Body.String(), "Internal Server Error") {
+		t.Fatalf("panic: %d %q", w.Code, w.Body.String())
+	}
+	w = gzipRequest(r, "/error", "gzip")
+	if w.Code != 400 || !strings.Contains(w.Body.String(), "bad input") {
+		t.Fatalf("error: %d %q", w.Code, w.Body.String())
+	}
+}
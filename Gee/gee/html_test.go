@@ -0,0 +1,45 @@
+package gee
+
+import (
+	"html/template"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadHTMLFuncMapOrder(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.tmpl"), []byte(`{{define "a.tmpl"}}{{up .}}{{end}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	SetMode(ReleaseMode)
+	defer SetMode(DebugMode)
+	r := New()
+	r.GET("/", func(c *Context) { c.HTML(200, "a.tmpl", "gee") })
+	render := func() (int, string) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		return w.Code, w.Body.String()
+	}
+
+	//函数尚未设置时返回解析错误
+	if err := r.LoadHTMLGlob(filepath.Join(dir, "*")); err == nil {
+		t.Fatal("LoadHTMLGlob with undefined function: want error")
+	}
+	if code, _ := render(); code != 500 {
+		t.Fatalf("render before SetFuncMap: status %d, want 500", code)
+	}
+	//拼错函数名时同样返回错误
+	if err := r.SetFuncMap(template.FuncMap{"upp": strings.ToUpper}); err == nil {
+		t.Fatal("SetFuncMap with misspelled function: want error")
+	}
+	//设置正确的函数后重新解析成功
+	if err := r.SetFuncMap(template.FuncMap{"up": strings.ToUpper}); err != nil {
+		t.Fatal(err)
+	}
+	if code, body := render(); code != 200 || body != "GEE" {
+		t.Fatalf("render after SetFuncMap: %d %q", code, body)
+	}
+}
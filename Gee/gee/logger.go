@@ -1,17 +1,269 @@
 package gee
+
 //日志中间件部分
 import (
+	"encoding/json"
+	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"net/http"
+	"os"
 	"time"
 )
 
+//终端颜色的控制字符
+const (
+	green   = "\033[97;42m"
+	white   = "\033[90;47m"
+	yellow  = "\033[90;43m"
+	red     = "\033[97;41m"
+	blue    = "\033[97;44m"
+	magenta = "\033[97;45m"
+	cyan    = "\033[97;46m"
+	reset   = "\033[0m"
+)
+
+//日志颜色模式
+type LogColorMode int
+
+const (
+	AutoColor    LogColorMode = iota //输出目标为终端时使用颜色
+	DisableColor                     //不使用颜色
+	ForceColor                       //总是使用颜色
+)
+
+//访问日志的格式化函数,返回的字符串应以换行结尾
+type LogFormatter func(params LogFormatterParams) string
+
+//访问日志中间件配置
+type LoggerConfig struct {
+	Formatter LogFormatter //日志格式化函数,为nil时使用默认格式,JSON为true时使用JSON格式
+	JSON      bool         //是否以每行一个JSON对象的格式输出
+	Output    io.Writer    //日志输出目标,为nil时使用Engine的结构化日志记录器,未设置记录器时使用log包的输出目标
+	SkipPaths []string     //不记录日志的请求路径
+	Color     LogColorMode //颜色模式,仅对默认格式有效
+}
+
+//传给日志格式化函数的请求信息
+type LogFormatterParams struct {
+	Request      *http.Request          //请求
+	TimeStamp    time.Time              //请求处理完成的时间
+	StatusCode   int                    //响应状态码
+	Latency      time.Duration          //处理耗时
+	RequestID    string                 //请求ID,未使用RequestID中间件时为空
+	ClientIP     string                 //客户端IP
+	Method       string                 //请求方法
+	Path         string                 //请求路径,包含查询字符串
+	UserAgent    string                 //客户端的User-Agent
+	BodySize     int                    //响应体大小
+	ErrorMessage string                 //处理过程中记录的私有错误
+	Keys         map[string]interface{} //上下文中的键值对
+	isTerm       bool                   //是否输出颜色
+}
+
+//返回状态码对应的颜色
+func (p *LogFormatterParams) StatusCodeColor() string {
+	switch code := p.StatusCode; {
+	case code >= 200 && code < 300:
+		return green
+	case code >= 300 && code < 400:
+		return white
+	case code >= 400 && code < 500:
+		return yellow
+	default:
+		return red
+	}
+}
+
+//返回请求方法对应的颜色
+func (p *LogFormatterParams) MethodColor() string {
+	switch p.Method {
+	case http.MethodGet:
+		return blue
+	case http.MethodPost:
+		return cyan
+	case http.MethodPut, http.MethodPatch:
+		return yellow
+	case http.MethodDelete:
+		return red
+	case http.MethodHead, http.MethodOptions:
+		return magenta
+	default:
+		return reset
+	}
+}
+
+//返回重置颜色的控制字符
+func (p *LogFormatterParams) ResetColor() string {
+	return reset
+}
+
+//判断是否输出颜色
+func (p *LogFormatterParams) IsOutputColor() bool {
+	return p.isTerm
+}
+
+//默认的日志格式
+var defaultLogFormatter = func(p LogFormatterParams) string {
+	var statusColor, methodColor, resetColor string
+	if p.IsOutputColor() {
+		statusColor, methodColor, resetColor = p.StatusCodeColor(), p.MethodColor(), p.ResetColor()
+	}
+	latency := p.Latency
+	if latency > time.Minute {
+		latency = latency.Truncate(time.Second)
+	}
+	var requestID string
+	if p.RequestID != "" {
+		requestID = " " + p.RequestID + " |"
+	}
+	return fmt.Sprintf("[GEE] %v |%s%s %3d %s| %13v | %15s | %5d |%s %-7s %s %#v\n%s",
+		p.TimeStamp.Format("2006/01/02 - 15:04:05"), requestID,
+		statusColor, p.StatusCode, resetColor,
+		latency, p.ClientIP, p.BodySize,
+		methodColor, p.Method, resetColor, p.Path,
+		p.ErrorMessage)
+}
+
+//JSON格式的日志
+var jsonLogFormatter = func(p LogFormatterParams) string {
+	entry := struct {
+		Time      string  `json:"time"`
+		RequestID string  `json:"request_id,omitempty"`
+		Status    int     `json:"status"`
+		Latency   float64 `json:"latency_ms"`
+		ClientIP  string  `json:"client_ip"`
+		Method    string  `json:"method"`
+		Path      string  `json:"path"`
+		UserAgent string  `json:"user_agent,omitempty"`
+		BodySize  int     `json:"body_size"`
+		Error     string  `json:"error,omitempty"`
+	}{
+		Time:      p.TimeStamp.Format(time.RFC3339Nano),
+		RequestID: p.RequestID,
+		Status:    p.StatusCode,
+		Latency:   float64(p.Latency) / float64(time.Millisecond),
+		ClientIP:  p.ClientIP,
+		Method:    p.Method,
+		Path:      p.Path,
+		UserAgent: p.UserAgent,
+		BodySize:  p.BodySize,
+		Error:     p.ErrorMessage,
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Sprintf("{\"error\":%q}\n", err.Error())
+	}
+	return string(data) + "\n"
+}
+
 //返回日志中间件函数
 func Logger() HandlerFunc {
+	return LoggerWithConfig(LoggerConfig{})
+}
+
+//返回以formatter格式化日志的日志中间件函数
+func LoggerWithFormatter(formatter LogFormatter) HandlerFunc {
+	return LoggerWithConfig(LoggerConfig{Formatter: formatter})
+}
+
+//返回输出到out的日志中间件函数
+func LoggerWithWriter(out io.Writer, skipPaths ...string) HandlerFunc {
+	return LoggerWithConfig(LoggerConfig{Output: out, SkipPaths: skipPaths})
+}
+
+//按配置返回日志中间件函数
+func LoggerWithConfig(config LoggerConfig) HandlerFunc {
+	formatter := config.Formatter
+	if formatter == nil {
+		formatter = defaultLogFormatter
+		if config.JSON {
+			formatter = jsonLogFormatter
+		}
+	}
+	out := config.Output
+	//未指定输出目标和格式时,若Engine设置了结构化日志记录器则写入该记录器
+	useEngineLogger := out == nil && config.Formatter == nil && !config.JSON
+	if out == nil {
+		out = log.Writer()
+	}
+	isTerm := config.Color == ForceColor ||
+		(config.Color == AutoColor && !config.JSON && isTerminal(out))
+	skip := make(map[string]struct{}, len(config.SkipPaths))
+	for _, path := range config.SkipPaths {
+		skip[path] = struct{}{}
+	}
 	return func(c *Context) {
-		t := time.Now()
+		start := time.Now()
+		path := c.Request.URL.Path
+		raw := c.Request.URL.RawQuery
 		c.Next()
-		//输出记录处理时间
-		log.Printf("[%d] %s in %v", c.StatusCode,
-			c.Request.RequestURI, time.Since(t))
+		if _, ok := skip[path]; ok {
+			return
+		}
+		if raw != "" {
+			path = path + "?" + raw
+		}
+		params := LogFormatterParams{
+			Request:      c.Request,
+			TimeStamp:    time.Now(),
+			StatusCode:   c.Writer.Status(),
+			ClientIP:     c.ClientIP(),
+			Method:       c.Request.Method,
+			Path:         path,
+			UserAgent:    c.Request.UserAgent(),
+			BodySize:     c.Writer.Size(),
+			ErrorMessage: c.Errors.ByType(ErrorTypePrivate).String(),
+			RequestID:    c.RequestID(),
+			Keys:         c.Keys,
+			isTerm:       isTerm,
+		}
+		params.Latency = params.TimeStamp.Sub(start)
+		if params.BodySize < 0 {
+			params.BodySize = 0 //尚未写入响应体
+		}
+		if useEngineLogger && c.engine.logger != nil {
+			logAccess(c, &params)
+			return
+		}
+		fmt.Fprint(out, formatter(params))
+	}
+}
+
+//以结构化属性写入请求的日志记录器,状态码为5xx时使用Error级别,4xx时使用Warn级别
+func logAccess(c *Context, p *LogFormatterParams) {
+	level := slog.LevelInfo
+	if p.StatusCode >= 500 {
+		level = slog.LevelError
+	} else if p.StatusCode >= 400 {
+		level = slog.LevelWarn
+	}
+	attrs := []slog.Attr{
+		slog.Int("status", p.StatusCode),
+		slog.Duration("latency", p.Latency),
+		slog.String("client_ip", p.ClientIP),
+		slog.String("user_agent", p.UserAgent),
+		slog.Int("body_size", p.BodySize),
+	}
+	if p.Request.URL.RawQuery != "" {
+		attrs = append(attrs, slog.String("query", p.Request.URL.RawQuery))
+	}
+	if p.ErrorMessage != "" {
+		attrs = append(attrs, slog.String("error", p.ErrorMessage))
+	}
+	c.Logger().LogAttrs(c, level, "request", attrs...)
+}
+
+//判断输出目标是否为终端
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
 	}
+	return info.Mode()&os.ModeCharDevice != 0 && os.Getenv("TERM") != "dumb"
 }
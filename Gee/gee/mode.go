@@ -0,0 +1,47 @@
+package gee
+
+//运行模式部分
+import (
+	"os"
+	"sync/atomic"
+)
+
+//设置运行模式的环境变量名
+const EnvGeeMode = "GEE_MODE"
+
+//运行模式
+const (
+	DebugMode   = "debug"   //调试模式,模板文件修改后自动重新加载
+	ReleaseMode = "release" //发布模式,模板只在加载时解析一次
+	TestMode    = "test"    //测试模式
+)
+
+//当前运行模式
+var geeMode atomic.Value
+
+//由环境变量初始化运行模式
+func init() {
+	SetMode(os.Getenv(EnvGeeMode))
+}
+
+//设置运行模式,为空时使用调试模式
+func SetMode(value string) {
+	switch value {
+	case "":
+		value = DebugMode
+	case DebugMode, ReleaseMode, TestMode:
+	default:
+		panic("gee mode unknown: " + value + " (available mode: debug release test)")
+	}
+	geeMode.Store(value)
+}
+
+//返回当前运行模式
+func Mode() string {
+	return geeMode.Load().(string)
+}
+
+//判断是否为调试模式
+func IsDebugging() bool {
+	return Mode() == DebugMode
+}
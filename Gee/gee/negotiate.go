@@ -0,0 +1,215 @@
+package gee
+
+//内容协商部分
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//常用的MIME类型
+const (
+	MIMEJSON  = "application/json"
+	MIMEHTML  = "text/html"
+	MIMEXML   = "application/xml"
+	MIMEXML2  = "text/xml"
+	MIMEPlain = "text/plain"
+)
+
+//内容协商配置,Offered为空时根据提供的数据自动确定可提供的格式
+type NegotiateConfig struct {
+	Offered  []string    //服务端可提供的MIME类型
+	HTMLName string      //HTML模板名
+	HTMLData interface{} //HTML模板渲染数据
+	JSONData interface{} //JSON数据
+	XMLData  interface{} //XML数据
+	Data     interface{} //未指定对应格式数据时使用的默认数据
+}
+
+//Accept首部中的一项
+type acceptItem struct {
+	mime    string  //MIME类型,可包含通配符
+	quality float64 //q值,表示偏好程度
+}
+
+//解析Accept首部,返回按q值从高到低排序的MIME类型,q值为0的项被忽略
+func parseAccept(acceptHeader string) []string {
+	items := parseAcceptItems(acceptHeader)
+	out := make([]string, len(items))
+	for i, item := range items {
+		out[i] = item.mime
+	}
+	return out
+}
+
+//解析Accept类首部(Accept、Accept-Encoding等),返回按q值从高到低排序的项,q值为0的项被忽略
+func parseAcceptItems(acceptHeader string) []acceptItem {
+	all := parseAcceptHeader(acceptHeader)
+	items := make([]acceptItem, 0, len(all))
+	for _, item := range all {
+		if item.quality > 0 {
+			items = append(items, item)
+		}
+	}
+	//q值相同时保持原有顺序
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].quality > items[j].quality
+	})
+	return items
+}
+
+//按首部中的顺序解析Accept类首部的所有项,包括q值为0即明确拒绝的项
+func parseAcceptHeader(acceptHeader string) []acceptItem {
+	parts := strings.Split(acceptHeader, ",")
+	items := make([]acceptItem, 0, len(parts))
+	for _, part := range parts {
+		params := strings.Split(part, ";")
+		mime := strings.TrimSpace(params[0])
+		if mime == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+				quality = q
+			}
+		}
+		items = append(items, acceptItem{mime: mime, quality: quality})
+	}
+	return items
+}
+
+//返回接受的MIME类型(可含通配符)匹配提供的MIME类型的具体程度,不区分大小写,不匹配时为0
+//完全匹配为3,type/*为2,*/*为1
+func mimeSpecificity(accepted, offered string) int {
+	accepted, offered = strings.ToLower(accepted), strings.ToLower(offered)
+	switch {
+	case accepted == offered:
+		return 3
+	case strings.HasSuffix(accepted, "/*") && strings.HasPrefix(offered, accepted[:len(accepted)-1]):
+		//处理 type/* 形式的通配
+		return 2
+	case accepted == "*/*" || accepted == "*":
+		return 1
+	}
+	return 0
+}
+
+//返回请求可接受的MIME类型,按偏好排序
+func (c *Context) Accepted() []string {
+	return parseAccept(c.Request.Header.Get("Accept"))
+}
+
+//根据请求的Accept首部在提供的格式中选择最合适的一个,无可接受的格式返回空字符串
+func (c *Context) NegotiateFormat(offered ...string) string {
+	if len(offered) == 0 {
+		panic("you must provide at least one offer")
+	}
+	header := strings.TrimSpace(c.Request.Header.Get("Accept"))
+	//未指定Accept时选择第一个提供的格式
+	if header == "" {
+		return offered[0]
+	}
+	accepted := parseAcceptHeader(header)
+	chosen, chosenQuality, chosenIndex := "", 0.0, len(accepted)
+	for _, offer := range offered {
+		//每个提供的格式取最具体的匹配项的q值,使"application/json;q=0, */*"能拒绝JSON
+		quality, index, specificity := 0.0, -1, 0
+		for i, item := range accepted {
+			if s := mimeSpecificity(item.mime, offer); s > specificity {
+				quality, index, specificity = item.quality, i, s
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+		//q值相同时选择匹配项在Accept中更靠前的格式,再相同时按提供的顺序
+		if quality > chosenQuality || (quality == chosenQuality && index < chosenIndex) {
+			chosen, chosenQuality, chosenIndex = offer, quality, index
+		}
+	}
+	return chosen
+}
+
+//Negotiate支持的MIME类型
+var negotiableMIMEs = []string{MIMEJSON, MIMEHTML, MIMEXML, MIMEXML2, MIMEPlain}
+
+//根据内容协商结果选择渲染方式构造响应,无可接受格式时以406中断
+//Offered只能包含JSON、HTML、XML和纯文本格式,纯文本以fmt.Sprint格式化Data,提供HTML时必须指定HTMLName
+func (c *Context) Negotiate(code int, config NegotiateConfig) {
+	offered := config.Offered
+	if len(offered) == 0 {
+		offered = config.offers()
+	}
+	for _, offer := range offered {
+		if !isNegotiable(offer) {
+			panic("negotiate: unsupported offered format " + offer)
+		}
+		if strings.EqualFold(offer, MIMEHTML) && config.HTMLName == "" {
+			panic("negotiate: HTMLName is required when offering " + MIMEHTML)
+		}
+	}
+	switch strings.ToLower(c.NegotiateFormat(offered...)) {
+	case MIMEJSON:
+		data := chooseData(config.JSONData, config.Data)
+		c.JSON(code, data)
+	case MIMEHTML:
+		data := chooseData(config.HTMLData, config.Data)
+		c.HTML(code, config.HTMLName, data)
+	case MIMEXML, MIMEXML2:
+		data := chooseData(config.XMLData, config.Data)
+		c.XML(code, data)
+	case MIMEPlain:
+		c.String(code, "%s", fmt.Sprint(config.Data))
+	default:
+		c.AbortWithStatus(http.StatusNotAcceptable)
+		c.Error(errors.New("the accepted formats are not offered by the server")).
+			SetType(ErrorTypePublic)
+	}
+}
+
+//判断Negotiate是否支持渲染该MIME类型
+func isNegotiable(offer string) bool {
+	for _, mime := range negotiableMIMEs {
+		if strings.EqualFold(offer, mime) {
+			return true
+		}
+	}
+	return false
+}
+
+//根据配置中提供的数据确定可提供的格式
+func (config *NegotiateConfig) offers() []string {
+	var offered []string
+	if config.JSONData != nil || config.Data != nil {
+		offered = append(offered, MIMEJSON)
+	}
+	if config.HTMLName != "" {
+		offered = append(offered, MIMEHTML)
+	}
+	if config.XMLData != nil || config.Data != nil {
+		offered = append(offered, MIMEXML, MIMEXML2)
+	}
+	if config.Data != nil {
+		offered = append(offered, MIMEPlain)
+	}
+	if len(offered) == 0 {
+		offered = append(offered, MIMEJSON)
+	}
+	return offered
+}
+
+//优先选择指定格式的数据,未指定时使用默认数据
+func chooseData(custom, wildcard interface{}) interface{} {
+	if custom != nil {
+		return custom
+	}
+	return wildcard
+}
@@ -0,0 +1,63 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", MIMEJSON},
+		{"application/xml", MIMEXML},
+		{"Application/XML", MIMEXML},
+		{"text/*;q=0.5, application/json;q=0.4", MIMEHTML},
+		{"application/json;q=0, application/xml;q=0", ""},
+		{"application/json;q=0, */*", MIMEHTML},
+		{"image/png", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		c := newContext(httptest.NewRecorder(), req)
+		if got := c.NegotiateFormat(MIMEJSON, MIMEHTML, MIMEXML); got != tt.want {
+			t.Errorf("Accept %q: got %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	r := New()
+	r.GET("/", func(c *Context) {
+		c.Negotiate(http.StatusOK, NegotiateConfig{Offered: []string{MIMEJSON, MIMEPlain}, Data: "hi"})
+	})
+	for accept, want := range map[string]int{
+		"text/plain":           http.StatusOK,
+		"application/json;q=0": http.StatusNotAcceptable,
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("Accept %q: status %d, want %d", accept, w.Code, want)
+		}
+	}
+
+	//仅指定Data时也提供纯文本格式
+	r.GET("/data", func(c *Context) {
+		c.Negotiate(http.StatusOK, NegotiateConfig{Data: H{"a": 1}})
+	})
+	req := httptest.NewRequest("GET", "/data", nil)
+	req.Header.Set("Accept", "text/plain")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "map[a:1]" {
+		t.Errorf("Accept text/plain with Data: %d %q", w.Code, w.Body.String())
+	}
+}
@@ -2,43 +2,146 @@ package gee
 
 //错误恢复中间件
 import (
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
-	"runtime"
+	"net/http/httputil"
 	"strings"
+	"syscall"
+	"time"
 )
 
-//输出调用栈信息
-func trace(message string) string {
-	var pcs [32]uintptr             //指针
-	n := runtime.Callers(3, pcs[:]) //获取调用函数指针(跳过前3个)
-	var str strings.Builder         //快速字符串拼接
-	str.WriteString(message)        //拼接字符串
-	str.WriteString("\nTraceback:")
-	for _, pc := range pcs[:n] { //遍历函数指针
-		fn := runtime.FuncForPC(pc)   //返回函数指针对应函数
-		file, line := fn.FileLine(pc) //文件及行号
-		str.WriteString(fmt.Sprintf("\n\t%s:%d", file, line))
-	}
-	return str.String() //转字符串
+//输出错误信息和调用栈
+func trace(message string, stack Stack) string {
+	return message + "\nTraceback:" + stack.String()
+}
+
+//错误恢复的处理函数,err为recover()得到的值
+type RecoveryFunc func(c *Context, err interface{})
+
+//dump请求时隐藏值的敏感首部
+var sensitiveHeaders = []string{
+	"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key",
 }
 
 //返回错误恢复中间件函数
 func Recovery() HandlerFunc {
-	//返回中间件函数
+	return RecoveryWithConfig(nil, nil)
+}
+
+//按配置返回错误恢复中间件函数
+//handler为恢复后构造响应的函数,为nil时返回500 JSON响应;
+//writer为调用栈等信息的输出目标,为nil时使用Engine的结构化日志记录器或log包
+//客户端连接已断开(broken pipe或connection reset)时只记录错误,不构造响应;
+//响应首部已写出时也不再调用handler,避免向已开始的响应追加内容
+func RecoveryWithConfig(handler RecoveryFunc, writer io.Writer) HandlerFunc {
+	if handler == nil {
+		handler = defaultRecoveryHandler
+	}
 	return func(c *Context) {
-		//错误处理
 		defer func() {
-			//恢复错误并输出错误信息
-			if err := recover(); err != nil {
-				message := fmt.Sprintf("%s", err)
-				log.Printf("%s\n\n", trace(message))
-				//终止请求的处理
-				c.Fail(http.StatusInternalServerError,
-					"Internal Server Error")
+			err := recover()
+			if err == nil {
+				return
+			}
+			message := fmt.Sprintf("%s", err)
+			brokenPipe := isBrokenPipe(err)
+			dump := dumpRequest(c.Request)
+			var stack Stack
+			var traceback string
+			if !brokenPipe {
+				//连接断开属于正常情况,无需调用栈
+				stack = captureStack(1, IsDebugging()) //调试模式下附带源代码行
+				traceback = trace(message, stack)
+				c.Set(PanicStackKey, stack)
+			}
+			var requestID string
+			if id := c.RequestID(); id != "" {
+				requestID = " request_id=" + id
+			}
+			switch {
+			case writer != nil:
+				if brokenPipe {
+					fmt.Fprintf(writer, "[Recovery] %s%s connection broken: %s\n%s\n",
+						time.Now().Format("2006/01/02 - 15:04:05"), requestID, message, dump)
+				} else {
+					fmt.Fprintf(writer, "[Recovery] %s%s panic recovered:\n%s\n%s\n\n",
+						time.Now().Format("2006/01/02 - 15:04:05"), requestID, dump, traceback)
+				}
+			case c.engine.logger != nil:
+				if brokenPipe {
+					c.Logger().Warn("connection broken", "error", message, "request", dump)
+				} else {
+					c.Logger().Error("panic recovered", "error", message, "request", dump, "stack", stack)
+				}
+			default:
+				if brokenPipe {
+					log.Printf("[Recovery]%s connection broken: %s\n%s\n", requestID, message, dump)
+				} else {
+					log.Printf("[Recovery]%s panic recovered:\n%s\n%s\n\n", requestID, dump, traceback)
+				}
+			}
+			if brokenPipe {
+				if e, ok := err.(error); ok {
+					c.Error(e)
+				}
+				c.Abort() //连接已断开,无法再写入响应
+				return
+			}
+			if c.Writer.Written() {
+				c.Abort() //响应已开始写出,无法再修改状态码
+				return
 			}
+			handler(c, err)
 		}()
-		c.Next() //用于跳转至下一中间件(可省略)
+		c.Next()
+	}
+}
+
+//默认的恢复处理函数,终止请求的处理并返回500
+func defaultRecoveryHandler(c *Context, err interface{}) {
+	c.AbortWithStatusJSON(http.StatusInternalServerError,
+		H{"message": "Internal Server Error"})
+}
+
+//判断panic是否由客户端连接断开引起
+func isBrokenPipe(err interface{}) bool {
+	e, ok := err.(error)
+	if !ok {
+		return false
+	}
+	if errors.Is(e, syscall.EPIPE) || errors.Is(e, syscall.ECONNRESET) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(e, &opErr) {
+		msg := strings.ToLower(opErr.Err.Error())
+		return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+	}
+	return false
+}
+
+//dump请求首部,隐藏敏感首部的值
+func dumpRequest(req *http.Request) string {
+	data, err := httputil.DumpRequest(req, false)
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\r\n"), "\r\n")
+	for i, line := range lines {
+		name, _, found := strings.Cut(line, ":")
+		if !found || i == 0 {
+			continue
+		}
+		for _, sensitive := range sensitiveHeaders {
+			if strings.EqualFold(strings.TrimSpace(name), sensitive) {
+				lines[i] = name + ": *"
+				break
+			}
+		}
 	}
+	return strings.Join(lines, "\n")
 }
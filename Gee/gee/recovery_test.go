@@ -0,0 +1,94 @@
+package gee
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestIsBrokenPipe(t *testing.T) {
+	tests := []struct {
+		err  interface{}
+		want bool
+	}{
+		{&net.OpError{Op: "write", Err: os.NewSyscallError("write", syscall.EPIPE)}, true},
+		{&net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, true},
+		{&net.OpError{Op: "write", Err: errors.New("write: broken pipe")}, true},
+		{errors.New("boom"), false},
+		{"broken pipe", false},
+	}
+	for _, tt := range tests {
+		if got := isBrokenPipe(tt.err); got != tt.want {
+			t.Errorf("isBrokenPipe(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestDumpRequestRedactsHeaders(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Cookie", "session=secret")
+	req.Header.Set("X-Api-Key", "secret")
+	req.Header.Set("Accept", "text/html")
+	dump := dumpRequest(req)
+	if strings.Contains(dump, "secret") {
+		t.Fatalf("dump leaks sensitive header values:\n%s", dump)
+	}
+	for _, want := range []string{"Authorization: *", "Cookie: *", "X-Api-Key: *", "Accept: text/html"} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("dump missing %q:\n%s", want, dump)
+		}
+	}
+}
+
+func TestRecoveryWithConfig(t *testing.T) {
+	var out bytes.Buffer
+	handlerCalled := false
+	r := New()
+	r.Use(RecoveryWithConfig(func(c *Context, err interface{}) {
+		handlerCalled = true
+		c.String(503, "recovered %v", err)
+	}, &out))
+	r.GET("/panic", func(c *Context) { panic("boom") })
+	r.GET("/written", func(c *Context) {
+		c.String(200, "partial")
+		panic("late")
+	})
+	r.GET("/broken", func(c *Context) {
+		panic(&net.OpError{Op: "write", Err: os.NewSyscallError("write", syscall.EPIPE)})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+	if w.Code != 503 || w.Body.String() != "recovered boom" {
+		t.Fatalf("panic: %d %q", w.Code, w.Body.String())
+	}
+	if !strings.Contains(out.String(), "panic recovered") {
+		t.Fatalf("panic not logged:\n%s", out.String())
+	}
+
+	//响应已写出时不再调用handler
+	handlerCalled = false
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/written", nil))
+	if handlerCalled || w.Code != 200 || w.Body.String() != "partial" {
+		t.Fatalf("written: handler called %v, %d %q", handlerCalled, w.Code, w.Body.String())
+	}
+
+	//连接断开时只记录错误,不构造响应
+	handlerCalled = false
+	out.Reset()
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/broken", nil))
+	if handlerCalled || w.Body.Len() != 0 {
+		t.Fatalf("broken pipe: handler called %v, body %q", handlerCalled, w.Body.String())
+	}
+	if !strings.Contains(out.String(), "connection broken") || strings.Contains(out.String(), "Traceback") {
+		t.Fatalf("broken pipe log:\n%s", out.String())
+	}
+}
@@ -0,0 +1,24 @@
+package render
+
+//普通数据渲染部分
+import "net/http"
+
+//普通数据渲染,ContentType为空时不设置Content-Type
+type Data struct {
+	ContentType string
+	Data        []byte
+}
+
+//渲染普通数据
+func (r Data) Render(w http.ResponseWriter) (err error) {
+	r.WriteContentType(w)
+	_, err = w.Write(r.Data)
+	return
+}
+
+//写入指定的Content-Type
+func (r Data) WriteContentType(w http.ResponseWriter) {
+	if r.ContentType != "" {
+		writeContentType(w, []string{r.ContentType})
+	}
+}
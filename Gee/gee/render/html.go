@@ -0,0 +1,56 @@
+package render
+
+//HTML渲染部分
+import (
+	"errors"
+	"html/template"
+	"net/http"
+)
+
+//HTML渲染器接口,根据模板名和数据生成对应的渲染
+type HTMLRender interface {
+	Instance(name string, data interface{}) Render
+}
+
+//单一模板集合的HTML渲染器,所有模板解析在同一个模板集合中
+type HTMLProduction struct {
+	Template *template.Template
+}
+
+//返回执行模板集合中名为name的模板的渲染
+func (r HTMLProduction) Instance(name string, data interface{}) Render {
+	return HTML{
+		Template: r.Template,
+		Name:     name,
+		Data:     data,
+	}
+}
+
+//HTML模板渲染,Name为空时执行Template本身
+type HTML struct {
+	Template *template.Template
+	Name     string
+	Data     interface{}
+}
+
+var htmlContentType = []string{"text/html; charset=utf-8"}
+
+//未加载模板时渲染返回的错误
+var errNoTemplates = errors.New("render: no HTML templates loaded")
+
+//渲染HTML模板
+func (r HTML) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	if r.Template == nil {
+		return errNoTemplates
+	}
+	if r.Name == "" {
+		return r.Template.Execute(w, r.Data)
+	}
+	return r.Template.ExecuteTemplate(w, r.Name, r.Data)
+}
+
+//写入HTML的Content-Type
+func (r HTML) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, htmlContentType)
+}
@@ -0,0 +1,70 @@
+package render
+
+//调试模式HTML渲染部分
+import (
+	"html/template"
+	"sync"
+	"time"
+)
+
+//调试模式的HTML渲染器,每次渲染前检查模板文件的修改时间,有变化时重新解析
+type HTMLDebug struct {
+	HTMLSource                  //模板来源
+	FuncMap    template.FuncMap //解析模板时使用的自定义函数
+
+	mu       sync.Mutex           //保护以下字段
+	tmpl     *template.Template   //最近一次解析的模板
+	modTimes map[string]time.Time //最近一次解析时各模板文件的修改时间
+}
+
+var _ HTMLRender = &HTMLDebug{}
+
+//返回执行名为name的模板的渲染,name为空时执行基础布局
+func (r *HTMLDebug) Instance(name string, data interface{}) Render {
+	tmpl, err := r.Load()
+	if err != nil {
+		return errorRender{err}
+	}
+	return HTML{
+		Template: tmpl,
+		Name:     name,
+		Data:     data,
+	}
+}
+
+//设置自定义函数并立即重新解析模板,解析失败时保留原有模板并返回错误
+//之后模板文件变化时以新的函数重新解析
+func (r *HTMLDebug) SetFuncMap(funcMap template.FuncMap) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.FuncMap = funcMap
+	modTimes, err := r.HTMLSource.modTimes()
+	if err != nil {
+		return err
+	}
+	tmpl, err := r.Parse(funcMap)
+	if err != nil {
+		return err
+	}
+	r.tmpl, r.modTimes = tmpl, modTimes
+	return nil
+}
+
+//返回最新的模板,模板文件有增删或修改时重新解析
+func (r *HTMLDebug) Load() (*template.Template, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	modTimes, err := r.HTMLSource.modTimes()
+	if err != nil {
+		return nil, err
+	}
+	if r.tmpl != nil && sameModTimes(r.modTimes, modTimes) {
+		return r.tmpl, nil
+	}
+	tmpl, err := r.Parse(r.FuncMap)
+	if err != nil {
+		return nil, err
+	}
+	r.tmpl, r.modTimes = tmpl, modTimes
+	return tmpl, nil
+}
@@ -0,0 +1,69 @@
+package render
+
+//可重新加载的HTML渲染部分
+import (
+	"html/template"
+	"sync"
+)
+
+//可重新加载的HTML渲染器
+//记录模板来源,自定义函数变化时重新解析,解析成功后才替换当前使用的模板,可在服务过程中安全调用
+type HTMLLoader struct {
+	source HTMLSource //模板来源
+	debug  bool       //是否为调试模式,调试模式下模板文件修改后自动重新解析
+
+	parseMu sync.Mutex   //串行化解析,保证最后一次调用的自定义函数生效
+	mu      sync.RWMutex //保护以下字段
+	current HTMLRender   //当前使用的渲染器,尚未成功解析时为nil
+	err     error        //尚未成功解析时最近一次解析的错误
+}
+
+var _ HTMLRender = &HTMLLoader{}
+
+//HTMLLoader构造函数,模板在调用SetFuncMap时才解析
+func NewHTMLLoader(source HTMLSource, debug bool) *HTMLLoader {
+	return &HTMLLoader{
+		source: source,
+		debug:  debug,
+	}
+}
+
+//以新的自定义函数重新解析模板并返回解析错误
+//解析失败时保留原有模板,尚未成功解析过时渲染返回该错误,可再次调用SetFuncMap修正
+func (l *HTMLLoader) SetFuncMap(funcMap template.FuncMap) error {
+	l.parseMu.Lock()
+	defer l.parseMu.Unlock()
+	var next HTMLRender
+	var err error
+	if l.debug {
+		debug := &HTMLDebug{HTMLSource: l.source, FuncMap: funcMap}
+		_, err = debug.Load()
+		next = debug
+	} else {
+		var tmpl *template.Template
+		tmpl, err = l.source.Parse(funcMap)
+		next = HTMLProduction{Template: tmpl}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err != nil {
+		l.err = err
+		return err
+	}
+	l.current, l.err = next, nil
+	return nil
+}
+
+//返回执行名为name的模板的渲染
+func (l *HTMLLoader) Instance(name string, data interface{}) Render {
+	l.mu.RLock()
+	current, err := l.current, l.err
+	l.mu.RUnlock()
+	if current == nil {
+		if err == nil {
+			err = errNoTemplates
+		}
+		return errorRender{err}
+	}
+	return current.Instance(name, data)
+}
@@ -0,0 +1,97 @@
+package render
+
+//HTML模板来源部分
+import (
+	"errors"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+//HTML模板的来源,为Glob、Files或FS+Layout+Patterns之一
+//记录来源以便在模板文件或自定义函数变化时重新解析
+type HTMLSource struct {
+	Glob     string   //模板文件的匹配模式
+	Files    []string //模板文件,Files[0]为基础布局
+	FS       fs.FS    //模板所在的文件系统,与Layout和Patterns配合使用
+	Layout   string   //FS中基础布局的路径,可为空
+	Patterns []string //FS中模板文件的匹配模式
+}
+
+//以自定义函数funcMap解析模板
+func (s *HTMLSource) Parse(funcMap template.FuncMap) (*template.Template, error) {
+	switch {
+	case s.FS != nil && s.Layout != "":
+		tmpl, err := template.New(path.Base(s.Layout)).Funcs(funcMap).ParseFS(s.FS, s.Layout)
+		if err != nil || len(s.Patterns) == 0 {
+			return tmpl, err
+		}
+		//布局之后解析的文件可覆盖布局中的block
+		return tmpl.ParseFS(s.FS, s.Patterns...)
+	case s.FS != nil:
+		return template.New("").Funcs(funcMap).ParseFS(s.FS, s.Patterns...)
+	case len(s.Files) > 0:
+		return template.New(filepath.Base(s.Files[0])).Funcs(funcMap).ParseFiles(s.Files...)
+	case s.Glob != "":
+		return template.New("").Funcs(funcMap).ParseGlob(s.Glob)
+	default:
+		return nil, errors.New("render: no template source specified")
+	}
+}
+
+//获取所有模板文件的修改时间
+func (s *HTMLSource) modTimes() (map[string]time.Time, error) {
+	modTimes := make(map[string]time.Time)
+	if s.FS != nil {
+		patterns := s.Patterns
+		if s.Layout != "" {
+			patterns = append([]string{s.Layout}, patterns...)
+		}
+		for _, pattern := range patterns {
+			matches, err := fs.Glob(s.FS, pattern)
+			if err != nil {
+				return nil, err
+			}
+			for _, match := range matches {
+				info, err := fs.Stat(s.FS, match)
+				if err != nil {
+					return nil, err
+				}
+				modTimes[match] = info.ModTime()
+			}
+		}
+		return modTimes, nil
+	}
+	files := s.Files
+	if len(files) == 0 {
+		matches, err := filepath.Glob(s.Glob)
+		if err != nil {
+			return nil, err
+		}
+		files = matches
+	}
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[file] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+//判断两次获取的修改时间是否相同
+func sameModTimes(a, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for file, modTime := range a {
+		if other, ok := b[file]; !ok || !other.Equal(modTime) {
+			return false
+		}
+	}
+	return true
+}
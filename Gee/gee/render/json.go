@@ -0,0 +1,168 @@
+package render
+
+//JSON渲染部分
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+)
+
+//JSON渲染
+type JSON struct {
+	Data interface{}
+}
+
+//带缩进的JSON渲染
+type IndentedJSON struct {
+	Data interface{}
+}
+
+//安全JSON渲染,数组数据前添加前缀以防止JSON劫持
+type SecureJSON struct {
+	Prefix string
+	Data   interface{}
+}
+
+//JSONP渲染,以回调函数包装JSON数据
+type JsonpJSON struct {
+	Callback string
+	Data     interface{}
+}
+
+//ASCII JSON渲染,非ASCII字符转义为\uXXXX
+type AsciiJSON struct {
+	Data interface{}
+}
+
+var (
+	jsonContentType      = []string{"application/json; charset=utf-8"}
+	jsonpContentType     = []string{"application/javascript; charset=utf-8"}
+	jsonASCIIContentType = []string{"application/json"}
+)
+
+//渲染JSON数据
+func (r JSON) Render(w http.ResponseWriter) error {
+	return WriteJSON(w, r.Data)
+}
+
+//写入JSON的Content-Type
+func (r JSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
+//编码JSON数据并写入响应
+func WriteJSON(w http.ResponseWriter, obj interface{}) error {
+	writeContentType(w, jsonContentType)
+	jsonBytes, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(jsonBytes)
+	return err
+}
+
+//渲染带缩进的JSON数据
+func (r IndentedJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	jsonBytes, err := json.MarshalIndent(r.Data, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(jsonBytes)
+	return err
+}
+
+//写入JSON的Content-Type
+func (r IndentedJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
+//渲染安全JSON数据
+func (r SecureJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	jsonBytes, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	//数据为数组时添加前缀
+	if bytes.HasPrefix(jsonBytes, []byte("[")) && bytes.HasSuffix(jsonBytes, []byte("]")) {
+		if _, err = w.Write([]byte(r.Prefix)); err != nil {
+			return err
+		}
+	}
+	_, err = w.Write(jsonBytes)
+	return err
+}
+
+//写入JSON的Content-Type
+func (r SecureJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
+//渲染JSONP数据,回调函数名为空时按普通JSON输出
+func (r JsonpJSON) Render(w http.ResponseWriter) (err error) {
+	r.WriteContentType(w)
+	ret, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	if r.Callback == "" {
+		_, err = w.Write(ret)
+		return err
+	}
+	//回调函数名只能为标识符或以"."连接的属性访问,防止注入脚本
+	if !ValidJSONPCallback(r.Callback) {
+		return ErrInvalidJSONPCallback
+	}
+	if _, err = w.Write([]byte(r.Callback + "(")); err != nil {
+		return err
+	}
+	if _, err = w.Write(ret); err != nil {
+		return err
+	}
+	_, err = w.Write([]byte(");"))
+	return err
+}
+
+//JSONP回调函数名不合法的错误
+var ErrInvalidJSONPCallback = errors.New("render: invalid JSONP callback name")
+
+//合法的JSONP回调函数名
+var jsonpCallbackPattern = regexp.MustCompile(`^[A-Za-z_$][0-9A-Za-z_$]*(\.[A-Za-z_$][0-9A-Za-z_$]*)*$`)
+
+//判断JSONP回调函数名是否合法
+func ValidJSONPCallback(callback string) bool {
+	return len(callback) <= 128 && jsonpCallbackPattern.MatchString(callback)
+}
+
+//写入JSONP的Content-Type
+func (r JsonpJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonpContentType)
+}
+
+//渲染ASCII JSON数据
+func (r AsciiJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	ret, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	var buffer bytes.Buffer
+	for _, r := range string(ret) {
+		cvt := string(r)
+		if r >= 128 {
+			cvt = fmt.Sprintf("\\u%04x", int64(r))
+		}
+		buffer.WriteString(cvt)
+	}
+	_, err = w.Write(buffer.Bytes())
+	return err
+}
+
+//写入ASCII JSON的Content-Type
+func (r AsciiJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonASCIIContentType)
+}
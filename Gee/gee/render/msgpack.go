@@ -0,0 +1,26 @@
+package render
+
+//MsgPack渲染部分
+import (
+	"net/http"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+//MsgPack渲染
+type MsgPack struct {
+	Data interface{}
+}
+
+var msgpackContentType = []string{"application/msgpack; charset=utf-8"}
+
+//渲染MsgPack数据
+func (r MsgPack) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return msgpack.NewEncoder(w).Encode(r.Data)
+}
+
+//写入MsgPack的Content-Type
+func (r MsgPack) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, msgpackContentType)
+}
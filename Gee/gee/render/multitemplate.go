@@ -0,0 +1,153 @@
+package render
+
+//多模板集合渲染部分
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"sync"
+)
+
+//多模板集合的HTML渲染器
+//每个模板集合独立解析,不同集合中的同名文件和同名block互不冲突;
+//集合中的第一个文件作为基础布局,其余文件可重新定义布局中的block或提供局部模板
+//Debug为true时各模板集合在模板文件修改后自动重新解析
+type HTMLTemplates struct {
+	Debug bool //是否为调试模式
+
+	parseMu sync.Mutex            //串行化添加模板来源和重新解析
+	mu      sync.RWMutex          //保护以下字段,使模板集合可在服务过程中安全修改
+	sets    map[string]HTMLRender //模板集合名到模板集合渲染器的映射
+	funcMap template.FuncMap      //解析模板时使用的自定义函数
+}
+
+var _ HTMLRender = &HTMLTemplates{}
+
+//HTMLTemplates构造函数,funcMap为解析模板时使用的自定义函数
+func NewHTMLTemplates(funcMap template.FuncMap) *HTMLTemplates {
+	return &HTMLTemplates{
+		sets:    make(map[string]HTMLRender),
+		funcMap: funcMap,
+	}
+}
+
+//添加已解析的模板集合,该集合不随SetFuncMap重新解析
+func (r *HTMLTemplates) Add(name string, tmpl *template.Template) error {
+	if tmpl == nil {
+		return errors.New("render: template can not be nil")
+	}
+	return r.addSet(name, HTMLProduction{Template: tmpl})
+}
+
+//添加模板集合渲染器
+func (r *HTMLTemplates) addSet(name string, set HTMLRender) error {
+	if len(name) == 0 {
+		return errors.New("render: template name cannot be empty")
+	}
+	r.mu.Lock()
+	r.sets[name] = set
+	r.mu.Unlock()
+	return nil
+}
+
+//由模板来源添加模板集合并返回解析错误
+//解析失败时仍记录该集合,渲染时返回错误,以便之后通过SetFuncMap补充自定义函数后重新解析
+func (r *HTMLTemplates) addSource(name string, source HTMLSource) error {
+	if len(name) == 0 {
+		return errors.New("render: template name cannot be empty")
+	}
+	r.parseMu.Lock()
+	defer r.parseMu.Unlock()
+	r.mu.RLock()
+	funcMap := r.funcMap
+	r.mu.RUnlock()
+	loader := NewHTMLLoader(source, r.Debug)
+	err := loader.SetFuncMap(funcMap)
+	r.addSet(name, loader)
+	return err
+}
+
+//由文件构建名为name的模板集合,files[0]为基础布局
+func (r *HTMLTemplates) AddFromFiles(name string, files ...string) error {
+	if len(files) == 0 {
+		return errors.New("html/template: no files named in call to AddFromFiles")
+	}
+	return r.addSource(name, HTMLSource{Files: files})
+}
+
+//由基础布局文件layout和匹配patterns的文件构建名为name的模板集合
+//patterns可匹配不同目录下的局部模板
+func (r *HTMLTemplates) AddFromGlob(name, layout string, patterns ...string) error {
+	files := []string{layout}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return err
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("html/template: pattern matches no files: %#q", pattern)
+		}
+		for _, match := range matches {
+			if match != layout {
+				files = append(files, match)
+			}
+		}
+	}
+	return r.AddFromFiles(name, files...)
+}
+
+//由文件系统fsys中的文件构建名为name的模板集合,layout为基础布局的路径
+func (r *HTMLTemplates) AddFromFS(name string, fsys fs.FS, layout string, patterns ...string) error {
+	return r.addSource(name, HTMLSource{FS: fsys, Layout: layout, Patterns: patterns})
+}
+
+//设置自定义函数并重新解析所有由模板来源添加的模板集合,返回遇到的第一个错误
+func (r *HTMLTemplates) SetFuncMap(funcMap template.FuncMap) error {
+	r.parseMu.Lock()
+	defer r.parseMu.Unlock()
+	r.mu.Lock()
+	r.funcMap = funcMap
+	loaders := make([]*HTMLLoader, 0, len(r.sets))
+	for _, set := range r.sets {
+		if loader, ok := set.(*HTMLLoader); ok {
+			loaders = append(loaders, loader)
+		}
+	}
+	r.mu.Unlock()
+	var firstErr error
+	for _, loader := range loaders {
+		if err := loader.SetFuncMap(funcMap); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+//返回执行名为name的模板集合的渲染,即以集合的基础布局渲染
+func (r *HTMLTemplates) Instance(name string, data interface{}) Render {
+	r.mu.RLock()
+	set, ok := r.sets[name]
+	r.mu.RUnlock()
+	if !ok {
+		return errorRender{fmt.Errorf("render: template set %q is not defined", name)}
+	}
+	return set.Instance("", data)
+}
+
+//渲染时返回错误的渲染,用于延迟报告查找模板失败的错误
+type errorRender struct {
+	err error
+}
+
+//返回记录的错误
+func (r errorRender) Render(http.ResponseWriter) error {
+	return r.err
+}
+
+//写入HTML的Content-Type
+func (r errorRender) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, htmlContentType)
+}
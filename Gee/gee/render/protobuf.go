@@ -0,0 +1,36 @@
+package render
+
+//ProtoBuf渲染部分
+import (
+	"errors"
+	"net/http"
+
+	"google.golang.org/protobuf/proto"
+)
+
+//ProtoBuf渲染,Data需实现proto.Message接口
+type ProtoBuf struct {
+	Data interface{}
+}
+
+var protobufContentType = []string{"application/x-protobuf"}
+
+//渲染ProtoBuf数据
+func (r ProtoBuf) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	message, ok := r.Data.(proto.Message)
+	if !ok {
+		return errors.New("render: protobuf data must implement proto.Message")
+	}
+	bytes, err := proto.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(bytes)
+	return err
+}
+
+//写入ProtoBuf的Content-Type
+func (r ProtoBuf) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, protobufContentType)
+}
@@ -0,0 +1,43 @@
+package render
+
+//流式数据渲染部分
+import (
+	"io"
+	"net/http"
+	"strconv"
+)
+
+//流式数据渲染,从Reader读取数据直接写入响应而不缓冲
+type Reader struct {
+	ContentType   string            //Content-Type
+	ContentLength int64             //数据长度,小于0时不设置Content-Length
+	Reader        io.Reader         //数据来源
+	Headers       map[string]string //额外的响应首部
+}
+
+//渲染流式数据
+func (r Reader) Render(w http.ResponseWriter) (err error) {
+	r.WriteContentType(w)
+	if r.ContentLength >= 0 {
+		//直接写入响应首部,不修改调用方传入的Headers
+		w.Header().Set("Content-Length", strconv.FormatInt(r.ContentLength, 10))
+	}
+	r.writeHeaders(w, r.Headers)
+	_, err = io.Copy(w, r.Reader)
+	return
+}
+
+//写入指定的Content-Type
+func (r Reader) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, []string{r.ContentType})
+}
+
+//写入额外的响应首部,已存在的首部不覆盖
+func (r Reader) writeHeaders(w http.ResponseWriter, headers map[string]string) {
+	header := w.Header()
+	for k, v := range headers {
+		if header.Get(k) == "" {
+			header.Set(k, v)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package render
+
+//响应渲染部分
+import "net/http"
+
+//渲染接口,每种响应格式实现该接口
+type Render interface {
+	//将数据编码并写入响应
+	Render(http.ResponseWriter) error
+	//写入对应的Content-Type首部
+	WriteContentType(w http.ResponseWriter)
+}
+
+var (
+	_ Render = JSON{}
+	_ Render = IndentedJSON{}
+	_ Render = SecureJSON{}
+	_ Render = JsonpJSON{}
+	_ Render = AsciiJSON{}
+	_ Render = XML{}
+	_ Render = YAML{}
+	_ Render = TOML{}
+	_ Render = ProtoBuf{}
+	_ Render = MsgPack{}
+	_ Render = String{}
+	_ Render = Data{}
+	_ Render = HTML{}
+	_ Render = SSEvent{}
+	_ Render = Reader{}
+)
+
+//未设置Content-Type时写入给定的值
+func writeContentType(w http.ResponseWriter, value []string) {
+	header := w.Header()
+	if val := header["Content-Type"]; len(val) == 0 {
+		header["Content-Type"] = value
+	}
+}
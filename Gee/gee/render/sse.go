@@ -0,0 +1,94 @@
+package render
+
+//服务器推送事件(SSE)渲染部分
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+//服务器推送事件,Data为字符串时原样输出,否则编码为JSON
+type SSEvent struct {
+	Event string      //事件名
+	Id    string      //事件ID,客户端重连时通过Last-Event-ID返回
+	Retry uint        //客户端重连间隔(毫秒),为0时不设置
+	Data  interface{} //事件数据
+}
+
+var sseContentType = []string{"text/event-stream"}
+
+//字段值中的换行符需转义,以免破坏事件格式
+var fieldReplacer = strings.NewReplacer(
+	"\n", "\\n",
+	"\r", "\\r")
+
+//数据中的每一行都需以"data:"开头
+var dataReplacer = strings.NewReplacer(
+	"\r\n", "\ndata:",
+	"\n", "\ndata:",
+	"\r", "\ndata:")
+
+//渲染服务器推送事件
+func (r SSEvent) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return EncodeSSE(w, r)
+}
+
+//写入SSE的Content-Type,同时禁止缓存
+func (r SSEvent) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, sseContentType)
+	header := w.Header()
+	if header.Get("Cache-Control") == "" {
+		header.Set("Cache-Control", "no-cache")
+	}
+}
+
+//按text/event-stream格式编码事件并写入w
+func EncodeSSE(w io.Writer, event SSEvent) error {
+	var buffer strings.Builder
+	if event.Id != "" {
+		buffer.WriteString("id:")
+		fieldReplacer.WriteString(&buffer, event.Id)
+		buffer.WriteString("\n")
+	}
+	if event.Event != "" {
+		buffer.WriteString("event:")
+		fieldReplacer.WriteString(&buffer, event.Event)
+		buffer.WriteString("\n")
+	}
+	if event.Retry > 0 {
+		fmt.Fprintf(&buffer, "retry:%d\n", event.Retry)
+	}
+	data, err := sseData(event.Data)
+	if err != nil {
+		return err
+	}
+	buffer.WriteString("data:")
+	dataReplacer.WriteString(&buffer, data)
+	buffer.WriteString("\n\n") //空行表示事件结束
+	_, err = io.WriteString(w, buffer.String())
+	return err
+}
+
+//将事件数据转换为字符串
+func sseData(data interface{}) (string, error) {
+	switch v := data.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case fmt.Stringer:
+		return v.String(), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64,
+		float32, float64, bool:
+		return fmt.Sprint(v), nil
+	default:
+		jsonBytes, err := json.Marshal(data)
+		if err != nil {
+			return "", err
+		}
+		return string(jsonBytes), nil
+	}
+}
@@ -0,0 +1,37 @@
+package render
+
+//文本渲染部分
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+//文本渲染,Data非空时按Format格式化
+type String struct {
+	Format string
+	Data   []interface{}
+}
+
+var plainContentType = []string{"text/plain; charset=utf-8"}
+
+//渲染文本数据
+func (r String) Render(w http.ResponseWriter) error {
+	return WriteString(w, r.Format, r.Data)
+}
+
+//写入文本的Content-Type
+func (r String) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, plainContentType)
+}
+
+//格式化文本并写入响应
+func WriteString(w http.ResponseWriter, format string, data []interface{}) (err error) {
+	writeContentType(w, plainContentType)
+	if len(data) > 0 {
+		_, err = fmt.Fprintf(w, format, data...)
+		return
+	}
+	_, err = io.WriteString(w, format)
+	return
+}
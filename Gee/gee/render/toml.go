@@ -0,0 +1,31 @@
+package render
+
+//TOML渲染部分
+import (
+	"net/http"
+
+	"github.com/BurntSushi/toml"
+)
+
+//TOML渲染
+type TOML struct {
+	Data interface{}
+}
+
+var tomlContentType = []string{"application/toml; charset=utf-8"}
+
+//渲染TOML数据
+func (r TOML) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	bytes, err := toml.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(bytes)
+	return err
+}
+
+//写入TOML的Content-Type
+func (r TOML) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, tomlContentType)
+}
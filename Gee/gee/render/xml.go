@@ -0,0 +1,25 @@
+package render
+
+//XML渲染部分
+import (
+	"encoding/xml"
+	"net/http"
+)
+
+//XML渲染
+type XML struct {
+	Data interface{}
+}
+
+var xmlContentType = []string{"application/xml; charset=utf-8"}
+
+//渲染XML数据
+func (r XML) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return xml.NewEncoder(w).Encode(r.Data)
+}
+
+//写入XML的Content-Type
+func (r XML) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, xmlContentType)
+}
@@ -0,0 +1,31 @@
+package render
+
+//YAML渲染部分
+import (
+	"net/http"
+
+	"gopkg.in/yaml.v3"
+)
+
+//YAML渲染
+type YAML struct {
+	Data interface{}
+}
+
+var yamlContentType = []string{"application/x-yaml; charset=utf-8"}
+
+//渲染YAML数据
+func (r YAML) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	bytes, err := yaml.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(bytes)
+	return err
+}
+
+//写入YAML的Content-Type
+func (r YAML) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, yamlContentType)
+}
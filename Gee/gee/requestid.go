@@ -0,0 +1,79 @@
+package gee
+
+//请求ID中间件部分
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+//默认的请求ID首部
+const DefaultRequestIDHeader = "X-Request-ID"
+
+//请求ID在Context中的键名
+const RequestIDKey = "gee/request-id"
+
+//请求ID的最大长度,超出或包含不可打印字符的请求ID将被重新生成
+const maxRequestIDLength = 128
+
+//请求ID中间件配置
+type RequestIDConfig struct {
+	Header    string        //读取和返回请求ID的首部,为空时使用X-Request-ID
+	Generator func() string //请求中没有有效的请求ID时生成新ID的函数,为nil时生成32位随机十六进制串
+}
+
+//返回请求ID中间件函数
+func RequestID() HandlerFunc {
+	return RequestIDWithConfig(RequestIDConfig{})
+}
+
+//按配置返回请求ID中间件函数
+//请求携带请求ID时沿用,以便跨服务关联日志,否则生成新ID;
+//请求ID存入Context并在响应首部中返回,Logger和Recovery的输出会附带该ID
+func RequestIDWithConfig(config RequestIDConfig) HandlerFunc {
+	if config.Header == "" {
+		config.Header = DefaultRequestIDHeader
+	}
+	if config.Generator == nil {
+		config.Generator = generateRequestID
+	}
+	return func(c *Context) {
+		id := c.GetHeader(config.Header)
+		if !validRequestID(id) {
+			id = config.Generator()
+		}
+		c.Set(RequestIDKey, id)
+		c.logger = nil //请求的日志记录器需附带新的请求ID
+		c.SetHeader(config.Header, id)
+		c.Next()
+	}
+}
+
+//获取请求ID,未使用RequestID中间件时返回空字符串
+func (c *Context) RequestID() string {
+	if id, ok := c.Get(RequestIDKey); ok {
+		if s, ok := id.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+//判断客户端提供的请求ID是否可用,避免过长或含控制字符的值污染日志
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+//生成16字节随机数的十六进制表示
+func generateRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,166 @@
+package gee
+
+//响应写入部分
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"log"
+	"net"
+	"net/http"
+)
+
+const (
+	noWritten     = -1            //尚未写入响应体时的大小
+	defaultStatus = http.StatusOK //默认响应状态码
+)
+
+//响应写入接口,在http.ResponseWriter基础上记录状态码、响应大小和写入状态
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Hijacker
+	http.Flusher
+	http.CloseNotifier
+
+	//返回当前响应的状态码
+	Status() int
+
+	//返回已写入响应体的字节数
+	Size() int
+
+	//写入字符串到响应体
+	WriteString(string) (int, error)
+
+	//判断响应首部是否已写入
+	Written() bool
+
+	//立即写入响应首部,之后不能再修改状态码和首部
+	WriteHeaderNow()
+
+	//返回HTTP/2服务器推送接口,不支持则返回nil
+	Pusher() http.Pusher
+}
+
+//响应写入结构体,包装了原始的http.ResponseWriter
+type responseWriter struct {
+	http.ResponseWriter     //原始响应
+	size                int //已写入响应体的字节数
+	status              int //响应状态码
+}
+
+var _ ResponseWriter = &responseWriter{}
+
+//重置为包装新的http.ResponseWriter
+func (w *responseWriter) reset(writer http.ResponseWriter) {
+	w.ResponseWriter = writer
+	w.size = noWritten
+	w.status = defaultStatus
+}
+
+//记录响应状态码,首部延迟至首次写入响应体或调用WriteHeaderNow时写入
+func (w *responseWriter) WriteHeader(code int) {
+	if code > 0 && w.status != code {
+		if w.Written() {
+			log.Printf("[WARNING] Headers were already written. Wanted to override status code %d with %d",
+				w.status, code)
+			return
+		}
+		w.status = code
+	}
+}
+
+//立即写入响应首部
+func (w *responseWriter) WriteHeaderNow() {
+	if !w.Written() {
+		w.size = 0
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+//写入响应体,未写入首部时先写入首部
+func (w *responseWriter) Write(data []byte) (n int, err error) {
+	w.WriteHeaderNow()
+	n, err = w.ResponseWriter.Write(data)
+	w.size += n
+	return
+}
+
+//写入字符串到响应体
+func (w *responseWriter) WriteString(s string) (n int, err error) {
+	w.WriteHeaderNow()
+	n, err = io.WriteString(w.ResponseWriter, s)
+	w.size += n
+	return
+}
+
+//返回响应状态码
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+//返回已写入响应体的字节数
+func (w *responseWriter) Size() int {
+	return w.size
+}
+
+//判断响应首部是否已写入
+func (w *responseWriter) Written() bool {
+	return w.size != noWritten
+}
+
+//实现http.Hijacker接口,接管底层连接
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if w.size < 0 {
+		w.size = 0
+	}
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return hijacker.Hijack()
+}
+
+//实现http.CloseNotifier接口,原始响应不支持时返回nil通道,接收时永远阻塞
+func (w *responseWriter) CloseNotify() <-chan bool {
+	notifier, ok := w.ResponseWriter.(http.CloseNotifier)
+	if !ok {
+		return nil
+	}
+	return notifier.CloseNotify()
+}
+
+//实现http.Flusher接口,刷新前先写入首部
+func (w *responseWriter) Flush() {
+	w.WriteHeaderNow()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+//返回HTTP/2服务器推送接口
+func (w *responseWriter) Pusher() (pusher http.Pusher) {
+	if pusher, ok := w.ResponseWriter.(http.Pusher); ok {
+		return pusher
+	}
+	return nil
+}
+
+//返回原始的http.ResponseWriter,供http.ResponseController使用
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+//缓冲响应写入结构体,响应体写入缓冲区,首部直接操作原始响应的首部
+//用于在渲染成功后再写出响应,避免渲染失败时写出不完整的响应
+type bufferedWriter struct {
+	bytes.Buffer             //响应体缓冲区
+	header       http.Header //原始响应的首部
+}
+
+//返回原始响应的首部
+func (w *bufferedWriter) Header() http.Header {
+	return w.header
+}
+
+//状态码由Context统一设置,此处忽略
+func (w *bufferedWriter) WriteHeader(int) {}
@@ -100,11 +100,19 @@ func (r *router) handle(c *Context) {
 		//将路由处理函数添加到上下文的处理函数集中
 		c.handlers = append(c.handlers, r.handlers[key])
 	} else {
-		//将404函数添加到上下文的处理函数集中
-		c.handlers = append(c.handlers, func(c *Context) {
-			c.String(http.StatusNotFound, "404 NOT FOUND :%s\n",
-				c.Path)
-		})
+		//将404处理函数添加到上下文的处理函数集中
+		c.SetStatus(http.StatusNotFound)
+		c.handlers = append(c.handlers, c.engine.noRouteHandlers()...)
 	}
 	c.Next()	//开始执行处理函数
 }
+
+//判断是否存在以pattern注册的路由
+func (r *router) hasPattern(pattern string) bool {
+	for key := range r.handlers {
+		if key[strings.Index(key, "-")+1:] == pattern {
+			return true
+		}
+	}
+	return false
+}
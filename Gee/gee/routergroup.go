@@ -2,16 +2,17 @@ package gee
 
 //路由分组部分
 import (
-	"log"
-	"net/http"
-	"path"
+	"gee/render"
+	"fmt"
+	"log/slog"
 )
 
 //路由分组结构体
 type RouterGroup struct {
-	prefix      string        //分组的前缀
-	middlewares []HandlerFunc //中间件函数集
-	engine      *Engine       //框架主体指针
+	prefix      string            //分组的前缀
+	middlewares []HandlerFunc     //中间件函数集
+	engine      *Engine           //框架主体指针
+	htmlRender  render.HTMLRender //分组的HTML渲染器,为nil时沿用父分组的渲染器
 }
 
 //由父路由分组创建新的路由分组
@@ -30,7 +31,9 @@ func (group *RouterGroup) Group(prefix string) *RouterGroup {
 func (group *RouterGroup) addRoute(method string, comp string, handler HandlerFunc) {
 	//完整的路由为分组前缀和当前添加的路径部分
 	pattern := group.prefix + comp
-	log.Printf("Route %4s - %s", method, pattern)
+	group.engine.logf(slog.LevelInfo, "route registered",
+		fmt.Sprintf("Route %4s - %s", method, pattern),
+		slog.String("method", method), slog.String("pattern", pattern))
 	//添加路由
 	group.engine.router.addRoute(method, pattern, handler)
 }
@@ -50,33 +53,17 @@ func (group *RouterGroup) Use(middlewares ...HandlerFunc) {
 	group.middlewares = append(group.middlewares, middlewares...)
 }
 
-//创建静态文件处理函数
-func (group *RouterGroup) createStaticHandler(relativePath string, fs http.FileSystem) HandlerFunc {
-	//绝对路径,由路由分组前缀和相对路径组成
-	absolutePath := path.Join(group.prefix, relativePath)
-	//文件服务器:会将请求的URL路径中"绝对路径"去除后,交由fs文件处理器处理
-	fileServer := http.StripPrefix(absolutePath, http.FileServer(fs))
-	//返回判断文件是否存在并执行文件系统服务的函数
-	return func(c *Context) {
-		//获取文件路径
-		file := c.GetParam("filepath")
-		//判断文件是否能打开(存在)
-		if _, err := fs.Open(file); err != nil {
-			c.SetStatus(http.StatusNotFound)
-			return
-		}
-		//执行服务器接口函数
-		fileServer.ServeHTTP(c.Writer, c.Request)
-	}
+//设置分组的HTML渲染器,分组及其子分组下的路由使用该渲染器渲染HTML
+//可在服务过程中调用,正在处理的请求仍使用原有渲染器
+func (group *RouterGroup) SetHTMLRender(r render.HTMLRender) {
+	group.engine.htmlMu.Lock()
+	group.htmlRender = r
+	group.engine.htmlMu.Unlock()
 }
 
-//添加静态文件路由
-//relativePath是文件的相对路径,root是映射到的项目目录
-func (group *RouterGroup) Static(relativePath string, root string) {
-	//静态文件处理函数
-	handler := group.createStaticHandler(relativePath, http.Dir(root))
-	//静态文件的模式字符串
-	urlPattern := path.Join(relativePath, "/*filepath")
-	//添加路由
-	group.GET(urlPattern, handler)
+//返回分组的HTML渲染器
+func (group *RouterGroup) getHTMLRender() render.HTMLRender {
+	group.engine.htmlMu.RLock()
+	defer group.engine.htmlMu.RUnlock()
+	return group.htmlRender
 }
@@ -0,0 +1,50 @@
+package gee
+
+//结构化日志部分
+import (
+	"context"
+	"log"
+	"log/slog"
+)
+
+//设置框架使用的结构化日志记录器
+//设置后路由注册、错误恢复的调用栈及未指定输出目标的访问日志均写入该记录器,为nil时恢复使用log包
+func (engine *Engine) SetLogger(logger *slog.Logger) {
+	engine.logger = logger
+}
+
+//返回框架使用的结构化日志记录器,未设置时返回slog.Default()
+func (engine *Engine) Logger() *slog.Logger {
+	if engine.logger == nil {
+		return slog.Default()
+	}
+	return engine.logger
+}
+
+//输出框架日志,设置了结构化日志记录器时以msg和attrs写入,否则以log包输出text
+func (engine *Engine) logf(level slog.Level, msg string, text string, attrs ...slog.Attr) {
+	if engine.logger == nil {
+		log.Print(text)
+		return
+	}
+	engine.logger.LogAttrs(context.Background(), level, msg, attrs...)
+}
+
+//返回当前请求的结构化日志记录器,附带请求ID、请求方法和路径属性
+func (c *Context) Logger() *slog.Logger {
+	if c.logger == nil {
+		var logger *slog.Logger
+		if c.engine != nil {
+			logger = c.engine.Logger()
+		} else {
+			logger = slog.Default()
+		}
+		var attrs []any
+		if id := c.RequestID(); id != "" {
+			attrs = append(attrs, slog.String("request_id", id))
+		}
+		attrs = append(attrs, slog.String("method", c.Method), slog.String("path", c.Path))
+		c.logger = logger.With(attrs...)
+	}
+	return c.logger
+}
@@ -0,0 +1,95 @@
+package gee
+
+//调用栈部分
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+//调用栈中的一帧
+type StackFrame struct {
+	Function string `json:"function"`         //函数全名,如"main.main.func1"
+	File     string `json:"file"`             //源文件路径
+	Line     int    `json:"line"`             //行号
+	Source   string `json:"source,omitempty"` //该行的源代码,未读取时为空
+}
+
+//调用栈,按从内到外的顺序排列
+type Stack []StackFrame
+
+//错误恢复后调用栈在Context中的键名,可在RecoveryFunc中通过c.Get获取用于错误上报
+const PanicStackKey = "gee/panic-stack"
+
+//获取调用栈,skip为跳过的调用层数,0表示captureStack的调用者
+//在panic恢复过程中调用时,从引发panic的函数开始记录;runtime包的帧均被去除
+//withSource为true时读取每一帧对应的源代码行
+func captureStack(skip int, withSource bool) Stack {
+	pcs := make([]uintptr, 64)
+	for {
+		n := runtime.Callers(skip+2, pcs)
+		if n < len(pcs) {
+			pcs = pcs[:n]
+			break
+		}
+		pcs = make([]uintptr, len(pcs)*2) //调用栈较深时扩大缓冲区
+	}
+	var stack Stack
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if frame.Function == "runtime.gopanic" {
+			stack = stack[:0] //丢弃recover一侧的帧,从panic处开始记录
+		} else if frame.Function != "" && !strings.HasPrefix(frame.Function, "runtime.") {
+			stack = append(stack, StackFrame{
+				Function: frame.Function,
+				File:     frame.File,
+				Line:     frame.Line,
+			})
+		}
+		if !more {
+			break
+		}
+	}
+	if withSource {
+		for i := range stack {
+			stack[i].Source = sourceLine(stack[i].File, stack[i].Line)
+		}
+	}
+	return stack
+}
+
+//格式化调用栈,每帧输出函数名和文件位置,有源代码时在下一行输出
+func (s Stack) String() string {
+	var str strings.Builder
+	for _, frame := range s {
+		fmt.Fprintf(&str, "\n\t%s\n\t\t%s:%d", frame.Function, frame.File, frame.Line)
+		if frame.Source != "" {
+			fmt.Fprintf(&str, "\n\t\t\t%s", frame.Source)
+		}
+	}
+	return str.String()
+}
+
+//已读取的源文件,按行分割
+var sourceCache sync.Map
+
+//读取源文件file的第line行,文件不存在时返回空字符串
+func sourceLine(file string, line int) string {
+	cached, ok := sourceCache.Load(file)
+	if !ok {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			data = nil //部署环境中通常没有源文件,缓存失败结果以免重复读取
+		}
+		cached, _ = sourceCache.LoadOrStore(file, bytes.Split(data, []byte("\n")))
+	}
+	lines := cached.([][]byte)
+	if line <= 0 || line > len(lines) {
+		return ""
+	}
+	return string(bytes.TrimSpace(lines[line-1]))
+}
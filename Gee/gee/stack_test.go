@@ -0,0 +1,36 @@
+package gee
+
+import (
+	"strings"
+	"testing"
+)
+
+//引发panic的函数,用于检查调用栈的起点
+func panickingFunction() {
+	panic("stack test")
+}
+
+func TestCaptureStack(t *testing.T) {
+	var stack Stack
+	func() {
+		defer func() {
+			recover()
+			stack = captureStack(0, true)
+		}()
+		panickingFunction()
+	}()
+	if len(stack) == 0 {
+		t.Fatal("empty stack")
+	}
+	if !strings.HasSuffix(stack[0].Function, ".panickingFunction") {
+		t.Fatalf("first frame = %s, want panickingFunction", stack[0].Function)
+	}
+	if stack[0].Source != `panic("stack test")` {
+		t.Fatalf("first frame source = %q", stack[0].Source)
+	}
+	for _, frame := range stack {
+		if strings.HasPrefix(frame.Function, "runtime.") {
+			t.Fatalf("runtime frame not trimmed: %s", frame.Function)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package gee
+
+//静态文件部分
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+//静态文件服务配置
+type StaticConfig struct {
+	ListDirectory bool     //目录无索引文件时是否列出目录内容,默认不列出并返回404
+	IndexFiles    []string //目录的索引文件,按顺序查找,为空时使用index.html
+	CacheControl  string   //Cache-Control首部的值,为空时不设置
+	ETag          bool     //是否根据文件修改时间和大小生成弱ETag
+	SPAFallback   bool     //文件不存在时返回根目录的索引文件,用于单页应用的前端路由
+}
+
+//返回目录的索引文件列表
+func (config *StaticConfig) indexFiles() []string {
+	if len(config.IndexFiles) == 0 {
+		return []string{"index.html"}
+	}
+	return config.IndexFiles
+}
+
+//创建静态文件处理函数
+func (group *RouterGroup) createStaticHandler(fsys http.FileSystem, config StaticConfig) HandlerFunc {
+	return func(c *Context) {
+		//获取文件路径,清理路径中的".."等以防止访问根目录之外的文件
+		name := path.Clean("/" + c.GetParam("filepath"))
+		f, info, err := openStatic(fsys, name)
+		if err != nil {
+			if config.SPAFallback {
+				if f, info, name = openIndex(fsys, "/", config.indexFiles()); f != nil {
+					defer f.Close()
+					serveStaticFile(c, fsys, f, info, name, config)
+					return
+				}
+			}
+			c.notFound()
+			return
+		}
+		defer f.Close()
+		if info.IsDir() {
+			//目录路径需以"/"结尾,以便页面中的相对路径正确解析
+			if !strings.HasSuffix(c.Request.URL.Path, "/") {
+				redirectToSlash(c)
+				return
+			}
+			if index, indexInfo, indexName := openIndex(fsys, name, config.indexFiles()); index != nil {
+				defer index.Close()
+				serveStaticFile(c, fsys, index, indexInfo, indexName, config)
+				return
+			}
+			if !config.ListDirectory {
+				c.notFound()
+				return
+			}
+			if err := listDirectory(c, f); err != nil {
+				c.Error(err)
+				c.notFound()
+			}
+			return
+		}
+		serveStaticFile(c, fsys, f, info, name, config)
+	}
+}
+
+//打开文件并获取文件信息,失败时关闭文件
+func openStatic(fsys http.FileSystem, name string) (http.File, os.FileInfo, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, info, nil
+}
+
+//在目录dir中按顺序查找索引文件,不存在时返回nil
+func openIndex(fsys http.FileSystem, dir string, indexFiles []string) (http.File, os.FileInfo, string) {
+	for _, index := range indexFiles {
+		name := path.Join(dir, index)
+		f, info, err := openStatic(fsys, name)
+		if err != nil {
+			continue
+		}
+		if info.IsDir() {
+			f.Close()
+			continue
+		}
+		return f, info, name
+	}
+	return nil, nil, ""
+}
+
+//按配置设置缓存首部并发送文件内容,支持Range和条件请求
+//存在客户端可接受的预压缩文件时发送压缩后的内容
+func serveStaticFile(c *Context, fsys http.FileSystem, f http.File, info os.FileInfo, name string, config StaticConfig) {
+	if encoding, cf, cinfo := openPrecompressed(c, fsys, name); cf != nil {
+		defer cf.Close()
+		//Content-Type由原文件名确定,避免根据压缩后的内容推断
+		contentType := mime.TypeByExtension(path.Ext(name))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		c.SetHeader("Content-Type", contentType)
+		c.SetHeader("Content-Encoding", encoding)
+		f, info = cf, cinfo
+	}
+	if config.CacheControl != "" {
+		c.SetHeader("Cache-Control", config.CacheControl)
+	}
+	if config.ETag {
+		c.SetHeader("ETag", fmt.Sprintf(`W/"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+	}
+	http.ServeContent(c.Writer, c.Request, name, info.ModTime(), f)
+}
+
+//预压缩文件的编码及文件后缀,按服务端偏好排序
+var precompressedEncodings = []struct {
+	encoding string //Content-Encoding的值
+	ext      string //预压缩文件的后缀
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+//查找与name同目录的预压缩文件,根据Accept-Encoding选择客户端可接受的编码
+//存在预压缩文件时添加Vary首部,无可用的预压缩文件时返回nil
+func openPrecompressed(c *Context, fsys http.FileSystem, name string) (string, http.File, os.FileInfo) {
+	accepted := parseAcceptItems(c.Request.Header.Get("Accept-Encoding"))
+	var chosen http.File
+	var chosenInfo os.FileInfo
+	chosenEncoding, chosenQuality, exists := "", 0.0, false
+	for _, candidate := range precompressedEncodings {
+		f, info, err := openStatic(fsys, name+candidate.ext)
+		if err != nil {
+			continue
+		}
+		if info.IsDir() {
+			f.Close()
+			continue
+		}
+		exists = true
+		//选择q值最高的编码,q值相同时按服务端偏好
+		quality := 0.0
+		for _, item := range accepted {
+			if strings.EqualFold(item.mime, candidate.encoding) {
+				quality = item.quality
+				break
+			}
+		}
+		if quality > chosenQuality {
+			if chosen != nil {
+				chosen.Close()
+			}
+			chosen, chosenInfo, chosenEncoding, chosenQuality = f, info, candidate.encoding, quality
+		} else {
+			f.Close()
+		}
+	}
+	if exists {
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+	}
+	return chosenEncoding, chosen, chosenInfo
+}
+
+//重定向到以"/"结尾的路径
+func redirectToSlash(c *Context) {
+	target := path.Base(c.Request.URL.Path) + "/"
+	if q := c.Request.URL.RawQuery; q != "" {
+		target += "?" + q
+	}
+	c.SetHeader("Location", target)
+	c.SetStatus(http.StatusMovedPermanently)
+}
+
+//列出目录内容
+func listDirectory(c *Context, dir http.File) error {
+	entries, err := dir.Readdir(-1)
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	var body strings.Builder
+	body.WriteString("<pre>\n")
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+		link := url.URL{Path: name}
+		fmt.Fprintf(&body, "<a href=\"%s\">%s</a>\n",
+			link.String(), template.HTMLEscapeString(name))
+	}
+	body.WriteString("</pre>\n")
+	c.SetHeader("Content-Type", "text/html; charset=utf-8")
+	c.Data(http.StatusOK, []byte(body.String()))
+	return nil
+}
+
+//添加单个静态文件路由
+//relativePath是路由路径,filepath是文件在服务器上的路径,存在预压缩文件时同样按Accept-Encoding发送
+func (group *RouterGroup) StaticFile(relativePath, filepath string) {
+	dir, file := path.Split(filepath)
+	if dir == "" {
+		dir = "."
+	}
+	group.staticFileHandler(relativePath, http.Dir(dir), "/"+file)
+}
+
+//添加文件系统中单个文件的路由,可用于go:embed嵌入的文件
+func (group *RouterGroup) StaticFileFS(relativePath, filepath string, fsys fs.FS) {
+	group.staticFileHandler(relativePath, http.FS(fsys), path.Clean("/"+filepath))
+}
+
+//添加发送文件系统fsys中文件name的路由
+func (group *RouterGroup) staticFileHandler(relativePath string, fsys http.FileSystem, name string) {
+	if strings.Contains(relativePath, ":") || strings.Contains(relativePath, "*") {
+		panic("URL parameters can not be used when serving a static file")
+	}
+	handler := func(c *Context) {
+		f, info, err := openStatic(fsys, name)
+		if err != nil {
+			c.notFound()
+			return
+		}
+		defer f.Close()
+		if info.IsDir() {
+			c.notFound()
+			return
+		}
+		serveStaticFile(c, fsys, f, info, name, StaticConfig{})
+	}
+	group.GET(relativePath, handler)
+	group.addRoute("HEAD", relativePath, handler)
+}
+
+//添加io/fs文件系统的静态文件路由,可用于go:embed嵌入的目录,config为可选的服务配置
+func (group *RouterGroup) StaticFS(relativePath string, fsys fs.FS, config ...StaticConfig) {
+	group.StaticFileSystem(relativePath, http.FS(fsys), config...)
+}
+
+//添加http.FileSystem文件系统的静态文件路由,config为可选的服务配置
+func (group *RouterGroup) StaticFileSystem(relativePath string, fsys http.FileSystem, config ...StaticConfig) {
+	if strings.Contains(relativePath, ":") || strings.Contains(relativePath, "*") {
+		panic("URL parameters can not be used when serving a static folder")
+	}
+	var cfg StaticConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	//静态文件处理函数
+	handler := group.createStaticHandler(fsys, cfg)
+	//静态文件的模式字符串
+	urlPattern := path.Join(relativePath, "/*filepath")
+	//添加路由
+	group.GET(urlPattern, handler)
+	group.addRoute("HEAD", urlPattern, handler)
+	//通配路由不匹配挂载点本身,单独注册以便访问根目录的索引文件和SPA回退
+	//前缀树忽略末尾的"/",该路由同时匹配relativePath和relativePath+"/"
+	root := strings.TrimSuffix(relativePath, "/")
+	if root == "" {
+		root = "/"
+	}
+	group.GET(root, handler)
+	group.addRoute("HEAD", root, handler)
+}
+
+//添加静态文件路由
+//relativePath是文件的相对路径,root是映射到的项目目录
+func (group *RouterGroup) Static(relativePath string, root string) {
+	group.StaticFileSystem(relativePath, http.Dir(root))
+}
@@ -0,0 +1,76 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestStaticFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":     {Data: []byte("root index")},
+		"app.js":         {Data: []byte("console.log(1)")},
+		"docs/readme.md": {Data: []byte("readme")},
+	}
+	r := New()
+	r.StaticFS("/spa", fsys, StaticConfig{SPAFallback: true})
+	r.StaticFS("/assets", fsys)
+
+	tests := []struct {
+		path     string
+		status   int
+		body     string
+		location string
+	}{
+		{"/spa/", http.StatusOK, "root index", ""},
+		{"/spa/app.js", http.StatusOK, "console.log(1)", ""},
+		{"/spa/some/client/route", http.StatusOK, "root index", ""},
+		{"/assets/", http.StatusOK, "root index", ""},
+		{"/assets", http.StatusMovedPermanently, "", "assets/"},
+		{"/assets/missing.js", http.StatusNotFound, "", ""},
+		{"/assets/docs", http.StatusMovedPermanently, "", "docs/"},
+		{"/assets/docs/", http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.status {
+			t.Errorf("GET %s: status %d, want %d", tt.path, w.Code, tt.status)
+			continue
+		}
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("GET %s: body %q, want %q", tt.path, w.Body.String(), tt.body)
+		}
+		if location := w.Header().Get("Location"); location != tt.location {
+			t.Errorf("GET %s: Location %q, want %q", tt.path, location, tt.location)
+		}
+	}
+}
+
+func TestStaticFileFSPrecompressed(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js":    {Data: []byte("console.log(1)")},
+		"app.js.gz": {Data: []byte("gzipped")},
+	}
+	r := New()
+	r.StaticFileFS("/app.js", "app.js", fsys)
+
+	req := httptest.NewRequest("GET", "/app.js", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Body.String() != "gzipped" {
+		t.Fatalf("gzip: encoding %q, body %q", w.Header().Get("Content-Encoding"), w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/javascript") {
+		t.Fatalf("gzip: Content-Type %q", ct)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/app.js", nil))
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != "console.log(1)" {
+		t.Fatalf("identity: encoding %q, body %q", w.Header().Get("Content-Encoding"), w.Body.String())
+	}
+}
@@ -0,0 +1,577 @@
+package gee
+
+//WebSocket部分(RFC 6455)
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+//WebSocket消息类型,即帧的操作码
+const (
+	TextMessage   = 1  //文本消息,内容须为UTF-8
+	BinaryMessage = 2  //二进制消息
+	CloseMessage  = 8  //关闭控制帧
+	PingMessage   = 9  //ping控制帧
+	PongMessage   = 10 //pong控制帧
+
+	continuationFrame = 0 //分片消息的后续帧
+)
+
+//WebSocket关闭状态码
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseMandatoryExtension      = 1010
+	CloseInternalServerErr       = 1011
+)
+
+const (
+	maxControlFramePayloadSize = 125                                    //控制帧负载的最大长度
+	websocketGUID              = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11" //计算Sec-WebSocket-Accept的固定GUID
+	closeWriteTimeout          = time.Second                            //发送关闭帧的超时时间
+)
+
+var (
+	//连接已发送关闭帧后继续写入时返回的错误
+	ErrWebSocketCloseSent = errors.New("websocket: close sent")
+	//消息超过读取大小限制时返回的错误
+	ErrWebSocketReadLimit = errors.New("websocket: read limit exceeded")
+)
+
+//收到关闭帧时返回的错误
+type CloseError struct {
+	Code int    //关闭状态码
+	Text string //关闭原因
+}
+
+//实现error接口
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Text)
+}
+
+//升级器未设置ReadLimit时单条消息的最大字节数
+const DefaultWebSocketReadLimit = 32 << 20
+
+//读取帧负载时预先分配的最大缓冲区大小
+const frameBufferPrealloc = 64 << 10
+
+//WebSocket升级器,用于在处理函数中将HTTP连接升级为WebSocket连接
+type WebSocketUpgrader struct {
+	Subprotocols     []string                   //服务端支持的子协议,按优先级排序
+	ReadLimit        int64                      //单条消息的最大字节数,不大于0时为DefaultWebSocketReadLimit
+	ReadBufferSize   int                        //读缓冲区大小,为0时沿用HTTP服务器的缓冲区
+	HandshakeTimeout time.Duration              //写入握手响应的超时时间,为0时不限制
+	CheckOrigin      func(r *http.Request) bool //检查Origin首部,为nil时要求与Host同源
+}
+
+//将当前请求升级为WebSocket连接,握手失败时以相应状态码中断并返回错误
+//成功后响应已被接管,处理函数不应再使用Context写入响应
+func (u *WebSocketUpgrader) Upgrade(c *Context) (*WebSocketConn, error) {
+	r := c.Request
+	if r.Method != http.MethodGet {
+		return nil, u.fail(c, http.StatusMethodNotAllowed, "request method is not GET")
+	}
+	if !headerContainsToken(r.Header, "Connection", "upgrade") {
+		return nil, u.fail(c, http.StatusBadRequest, "'upgrade' token not found in 'Connection' header")
+	}
+	if !headerContainsToken(r.Header, "Upgrade", "websocket") {
+		return nil, u.fail(c, http.StatusBadRequest, "'websocket' token not found in 'Upgrade' header")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		c.SetHeader("Sec-WebSocket-Version", "13")
+		return nil, u.fail(c, http.StatusUpgradeRequired, "unsupported version")
+	}
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = checkSameOrigin
+	}
+	if !checkOrigin(r) {
+		return nil, u.fail(c, http.StatusForbidden, "request origin not allowed")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, u.fail(c, http.StatusBadRequest, "'Sec-WebSocket-Key' header is missing or invalid")
+	}
+	subprotocol := u.selectSubprotocol(r)
+
+	//记录状态码供日志使用,随后接管底层连接
+	c.Writer.WriteHeader(http.StatusSwitchingProtocols)
+	netConn, brw, err := c.Writer.Hijack()
+	if err != nil {
+		return nil, u.fail(c, http.StatusInternalServerError, err.Error())
+	}
+	if brw.Reader.Buffered() > 0 {
+		netConn.Close()
+		return nil, errors.New("websocket: client sent data before handshake is complete")
+	}
+	br := brw.Reader
+	if u.ReadBufferSize > 0 {
+		br = bufio.NewReaderSize(netConn, u.ReadBufferSize)
+	}
+
+	var response strings.Builder
+	response.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	response.WriteString("Sec-WebSocket-Accept: " + computeAcceptKey(key) + "\r\n")
+	if subprotocol != "" {
+		response.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	response.WriteString("\r\n")
+	if u.HandshakeTimeout > 0 {
+		netConn.SetWriteDeadline(time.Now().Add(u.HandshakeTimeout))
+	}
+	if _, err = io.WriteString(netConn, response.String()); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	if u.HandshakeTimeout > 0 {
+		netConn.SetWriteDeadline(time.Time{})
+	}
+	conn := newWebSocketConn(netConn, br, true)
+	conn.subprotocol = subprotocol
+	if u.ReadLimit > 0 {
+		conn.SetReadLimit(u.ReadLimit)
+	}
+	return conn, nil
+}
+
+//握手失败时中断请求并返回错误
+func (u *WebSocketUpgrader) fail(c *Context, status int, reason string) error {
+	err := errors.New("websocket: " + reason)
+	c.AbortWithStatus(status)
+	c.Error(err).SetType(ErrorTypePublic)
+	return err
+}
+
+//从客户端请求的子协议中选择服务端支持的第一个
+func (u *WebSocketUpgrader) selectSubprotocol(r *http.Request) string {
+	requested := headerTokens(r.Header, "Sec-WebSocket-Protocol")
+	for _, supported := range u.Subprotocols {
+		for _, protocol := range requested {
+			if protocol == supported {
+				return protocol
+			}
+		}
+	}
+	return ""
+}
+
+//判断Origin首部与Host是否同源,无Origin首部时视为同源
+func checkSameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+//计算握手响应的Sec-WebSocket-Accept值
+func computeAcceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+//返回首部中以逗号分隔的所有值
+func headerTokens(header http.Header, name string) []string {
+	var tokens []string
+	for _, value := range header[http.CanonicalHeaderKey(name)] {
+		for _, token := range strings.Split(value, ",") {
+			if token = strings.TrimSpace(token); token != "" {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	return tokens
+}
+
+//判断首部是否包含指定的值(不区分大小写)
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, t := range headerTokens(header, name) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
+
+//WebSocket连接,读操作和写操作可分别在不同的goroutine中进行
+type WebSocketConn struct {
+	conn        net.Conn      //底层连接
+	br          *bufio.Reader //读缓冲
+	isServer    bool          //是否为服务端,服务端读取的帧须带掩码,写出的帧不带掩码
+	subprotocol string        //协商的子协议
+	readLimit   int64         //单条消息的最大字节数
+
+	writeMu       sync.Mutex //保证帧写入的完整性
+	closeSent     bool       //是否已发送关闭帧
+	writeDeadline time.Time  //消息帧的写超时
+
+	pingHandler func(appData string) error //收到ping时的处理函数
+	pongHandler func(appData string) error //收到pong时的处理函数
+}
+
+//WebSocketConn构造函数
+func newWebSocketConn(conn net.Conn, br *bufio.Reader, isServer bool) *WebSocketConn {
+	if br == nil {
+		br = bufio.NewReader(conn)
+	}
+	c := &WebSocketConn{
+		conn:      conn,
+		br:        br,
+		isServer:  isServer,
+		readLimit: DefaultWebSocketReadLimit,
+	}
+	c.SetPingHandler(nil)
+	c.SetPongHandler(nil)
+	return c
+}
+
+//返回协商的子协议
+func (c *WebSocketConn) Subprotocol() string {
+	return c.subprotocol
+}
+
+//返回对端地址
+func (c *WebSocketConn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+//设置单条消息的最大字节数,超过时以1009关闭连接,不大于0时不限制
+func (c *WebSocketConn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+//设置读超时
+func (c *WebSocketConn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+//设置消息帧的写超时,控制帧使用WriteControl指定的超时
+func (c *WebSocketConn) SetWriteDeadline(t time.Time) error {
+	c.writeMu.Lock()
+	c.writeDeadline = t
+	c.writeMu.Unlock()
+	return nil
+}
+
+//设置收到ping时的处理函数,为nil时默认回复相同负载的pong
+func (c *WebSocketConn) SetPingHandler(h func(appData string) error) {
+	if h == nil {
+		h = func(appData string) error {
+			err := c.WriteControl(PongMessage, []byte(appData), time.Now().Add(time.Second))
+			if err == ErrWebSocketCloseSent {
+				return nil
+			}
+			return err
+		}
+	}
+	c.pingHandler = h
+}
+
+//设置收到pong时的处理函数,为nil时忽略pong
+func (c *WebSocketConn) SetPongHandler(h func(appData string) error) {
+	if h == nil {
+		h = func(string) error { return nil }
+	}
+	c.pongHandler = h
+}
+
+//读取一条完整的消息,自动处理分片和控制帧
+//对端关闭连接时返回*CloseError
+func (c *WebSocketConn) ReadMessage() (messageType int, p []byte, err error) {
+	var message []byte
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch opcode {
+		case PingMessage:
+			if err := c.pingHandler(string(payload)); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			if err := c.pongHandler(string(payload)); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case CloseMessage:
+			return 0, nil, c.handleClose(payload)
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, c.protocolError("message start before final message frame")
+			}
+			messageType = opcode
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, c.protocolError("continuation after final message frame")
+			}
+		default:
+			return 0, nil, c.protocolError(fmt.Sprintf("unknown opcode %d", opcode))
+		}
+		if c.readLimit > 0 && int64(len(message))+int64(len(payload)) > c.readLimit {
+			c.writeCloseFrame(CloseMessageTooBig, "")
+			return 0, nil, ErrWebSocketReadLimit
+		}
+		message = append(message, payload...)
+		if fin {
+			break
+		}
+	}
+	if messageType == TextMessage && !utf8.Valid(message) {
+		c.writeCloseFrame(CloseInvalidFramePayloadData, "")
+		return 0, nil, errors.New("websocket: invalid utf8 payload in text message")
+	}
+	return messageType, message, nil
+}
+
+//读取一帧,返回是否为最后一帧、操作码和去除掩码后的负载
+func (c *WebSocketConn) readFrame() (fin bool, opcode int, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(c.br, header[:]); err != nil {
+		return
+	}
+	fin = header[0]&0x80 != 0
+	opcode = int(header[0] & 0x0f)
+	if header[0]&0x70 != 0 {
+		err = c.protocolError("unexpected reserved bits")
+		return
+	}
+	masked := header[1]&0x80 != 0
+	if masked != c.isServer {
+		err = c.protocolError("incorrect mask flag")
+		return
+	}
+	length := int64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+		if length < 0 {
+			err = c.protocolError("invalid payload length")
+			return
+		}
+	}
+	//控制帧不能分片且负载不超过125字节
+	if opcode >= CloseMessage {
+		if !fin {
+			err = c.protocolError("control frame not final")
+			return
+		}
+		if length > maxControlFramePayloadSize {
+			err = c.protocolError("control frame length > 125")
+			return
+		}
+	} else if c.readLimit > 0 && length > c.readLimit {
+		//在读取负载前拒绝过大的帧
+		c.writeCloseFrame(CloseMessageTooBig, "")
+		err = ErrWebSocketReadLimit
+		return
+	}
+	var maskKey [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.br, maskKey[:]); err != nil {
+			return
+		}
+	}
+	//随数据到达增长缓冲区,不按帧首部声明的长度预先分配,避免伪造的长度耗尽内存
+	var buf bytes.Buffer
+	if length < frameBufferPrealloc {
+		buf.Grow(int(length))
+	} else {
+		buf.Grow(frameBufferPrealloc)
+	}
+	if _, err = io.CopyN(&buf, c.br, length); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+	payload = buf.Bytes()
+	if masked {
+		maskBytes(maskKey, payload)
+	}
+	return
+}
+
+//处理对端的关闭帧,回复关闭帧并返回*CloseError
+func (c *WebSocketConn) handleClose(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatusReceived}
+	switch {
+	case len(payload) == 1:
+		return c.protocolError("invalid close payload")
+	case len(payload) >= 2:
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		if !isValidReceivedCloseCode(closeErr.Code) {
+			return c.protocolError("invalid close code")
+		}
+		if !utf8.Valid(payload[2:]) {
+			return c.protocolError("invalid utf8 payload in close frame")
+		}
+		closeErr.Text = string(payload[2:])
+	}
+	//按协议回复相同状态码的关闭帧
+	replyCode := closeErr.Code
+	if replyCode == CloseNoStatusReceived {
+		replyCode = CloseNormalClosure
+	}
+	c.writeCloseFrame(replyCode, "")
+	return closeErr
+}
+
+//以协议错误关闭连接并返回错误
+func (c *WebSocketConn) protocolError(message string) error {
+	c.writeCloseFrame(CloseProtocolError, "")
+	return errors.New("websocket: " + message)
+}
+
+//判断收到的关闭状态码是否合法
+func isValidReceivedCloseCode(code int) bool {
+	switch code {
+	case CloseNormalClosure, CloseGoingAway, CloseProtocolError, CloseUnsupportedData,
+		CloseInvalidFramePayloadData, ClosePolicyViolation, CloseMessageTooBig,
+		CloseMandatoryExtension, CloseInternalServerErr:
+		return true
+	}
+	//3000-4999为应用和库自定义的状态码
+	return code >= 3000 && code <= 4999
+}
+
+//发送一条消息,消息以单帧发送
+func (c *WebSocketConn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return c.WriteControl(messageType, data, time.Time{})
+	}
+	return c.writeFrame(true, messageType, data, time.Time{})
+}
+
+//发送控制帧,deadline为零值时不设置写超时
+func (c *WebSocketConn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	if messageType != CloseMessage && messageType != PingMessage && messageType != PongMessage {
+		return fmt.Errorf("websocket: bad control message type %d", messageType)
+	}
+	if len(data) > maxControlFramePayloadSize {
+		return errors.New("websocket: invalid control frame")
+	}
+	return c.writeFrame(true, messageType, data, deadline)
+}
+
+//编码并写出一帧,发送关闭帧后不再允许写入
+func (c *WebSocketConn) writeFrame(fin bool, opcode int, payload []byte, deadline time.Time) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return ErrWebSocketCloseSent
+	}
+	if opcode == CloseMessage {
+		c.closeSent = true
+	}
+
+	frame := make([]byte, 0, 14+len(payload))
+	b0 := byte(opcode)
+	if fin {
+		b0 |= 0x80
+	}
+	frame = append(frame, b0)
+	var maskBit byte
+	if !c.isServer {
+		maskBit = 0x80 //客户端发送的帧必须带掩码
+	}
+	length := len(payload)
+	switch {
+	case length <= maxControlFramePayloadSize:
+		frame = append(frame, maskBit|byte(length))
+	case length <= 0xffff:
+		frame = append(frame, maskBit|126, byte(length>>8), byte(length))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+	if c.isServer {
+		frame = append(frame, payload...)
+	} else {
+		var maskKey [4]byte
+		if _, err := rand.Read(maskKey[:]); err != nil {
+			return err
+		}
+		frame = append(frame, maskKey[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		maskBytes(maskKey, frame[start:])
+	}
+
+	if deadline.IsZero() {
+		deadline = c.writeDeadline
+	}
+	c.conn.SetWriteDeadline(deadline)
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+//发送关闭帧,忽略错误
+func (c *WebSocketConn) writeCloseFrame(code int, text string) {
+	c.WriteControl(CloseMessage, FormatCloseMessage(code, text), time.Now().Add(closeWriteTimeout))
+}
+
+//以指定的状态码和原因发起关闭握手,之后应继续ReadMessage直至收到对端的关闭帧
+func (c *WebSocketConn) CloseWithCode(code int, text string) error {
+	return c.WriteControl(CloseMessage, FormatCloseMessage(code, text), time.Now().Add(closeWriteTimeout))
+}
+
+//关闭底层连接,不发送关闭帧
+func (c *WebSocketConn) Close() error {
+	return c.conn.Close()
+}
+
+//构造关闭帧的负载,状态码为1005时负载为空
+func FormatCloseMessage(code int, text string) []byte {
+	if code == CloseNoStatusReceived {
+		return []byte{}
+	}
+	buf := make([]byte, 2+len(text))
+	binary.BigEndian.PutUint16(buf, uint16(code))
+	copy(buf[2:], text)
+	return buf
+}
+
+//对负载应用掩码(掩码与去掩码操作相同)
+func maskBytes(key [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= key[i&3]
+	}
+}
@@ -0,0 +1,168 @@
+package gee
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+//测试用的WebSocket客户端,完成握手并返回客户端连接
+func dialWebSocket(t *testing.T, serverURL string) *WebSocketConn {
+	addr := strings.TrimPrefix(serverURL, "http://")
+	netConn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	req, _ := http.NewRequest("GET", serverURL+"/ws", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Protocol", "chat, superchat")
+	if err := req.Write(netConn); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(netConn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake status = %d, want 101", resp.StatusCode)
+	}
+	//RFC 6455 1.3节中的示例值
+	if accept := resp.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Sec-WebSocket-Accept = %q", accept)
+	}
+	conn := newWebSocketConn(netConn, br, false)
+	conn.subprotocol = resp.Header.Get("Sec-WebSocket-Protocol")
+	return conn
+}
+
+//启动一个回显消息的WebSocket服务
+func newEchoServer(upgrader *WebSocketUpgrader) *httptest.Server {
+	r := New()
+	r.GET("/ws", func(c *Context) {
+		conn, err := upgrader.Upgrade(c)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(messageType, data); err != nil {
+				return
+			}
+		}
+	})
+	return httptest.NewServer(r)
+}
+
+func TestWebSocketEcho(t *testing.T) {
+	server := newEchoServer(&WebSocketUpgrader{Subprotocols: []string{"superchat"}})
+	defer server.Close()
+	conn := dialWebSocket(t, server.URL)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	if conn.Subprotocol() != "superchat" {
+		t.Fatalf("subprotocol = %q, want superchat", conn.Subprotocol())
+	}
+	//中等长度和较长的消息分别使用16位和64位的长度编码
+	for _, message := range [][]byte{[]byte("hello"), bytes.Repeat([]byte("a"), 1000), bytes.Repeat([]byte("b"), 70000)} {
+		if err := conn.WriteMessage(BinaryMessage, message); err != nil {
+			t.Fatal(err)
+		}
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if messageType != BinaryMessage || !bytes.Equal(data, message) {
+			t.Fatalf("echo of %d bytes mismatched", len(message))
+		}
+	}
+
+	//分片消息中间插入ping控制帧
+	var pong string
+	conn.SetPongHandler(func(appData string) error {
+		pong = appData
+		return nil
+	})
+	conn.writeFrame(false, TextMessage, []byte("frag"), time.Time{})
+	conn.WriteControl(PingMessage, []byte("p"), time.Time{})
+	conn.writeFrame(true, continuationFrame, []byte("ment"), time.Time{})
+	messageType, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messageType != TextMessage || string(data) != "fragment" || pong != "p" {
+		t.Fatalf("got type=%d data=%q pong=%q", messageType, data, pong)
+	}
+
+	//关闭握手
+	if err := conn.CloseWithCode(CloseNormalClosure, "bye"); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = conn.ReadMessage()
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != CloseNormalClosure {
+		t.Fatalf("got %v, want close 1000", err)
+	}
+}
+
+func TestWebSocketReadLimit(t *testing.T) {
+	server := newEchoServer(&WebSocketUpgrader{ReadLimit: 4})
+	defer server.Close()
+	conn := dialWebSocket(t, server.URL)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	conn.WriteMessage(TextMessage, []byte("too long"))
+	_, _, err := conn.ReadMessage()
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != CloseMessageTooBig {
+		t.Fatalf("got %v, want close 1009", err)
+	}
+}
+
+func TestWebSocketOversizedFrame(t *testing.T) {
+	server := newEchoServer(&WebSocketUpgrader{})
+	defer server.Close()
+	conn := dialWebSocket(t, server.URL)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	//声明2^62字节负载的帧首部,不发送负载
+	frame := []byte{0x80 | BinaryMessage, 0x80 | 127, 0x40, 0, 0, 0, 0, 0, 0, 0, 1, 2, 3, 4}
+	if _, err := conn.conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := conn.ReadMessage()
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != CloseMessageTooBig {
+		t.Fatalf("got %v, want close 1009", err)
+	}
+}
+
+func TestWebSocketHandshakeFailure(t *testing.T) {
+	server := newEchoServer(&WebSocketUpgrader{})
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", resp.StatusCode)
+	}
+}
@@ -1,7 +1,15 @@
 module main
 
-go 1.14
+go 1.23
 
 require gee v0.0.0
 
+require (
+	github.com/BurntSushi/toml v1.6.0 // indirect
+	github.com/vmihailenco/msgpack/v5 v5.4.1 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	google.golang.org/protobuf v1.36.12 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
 replace gee => ./gee